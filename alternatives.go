@@ -0,0 +1,257 @@
+package gosft
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Era describes a single entry of an era table, such as the Japanese
+// imperial eras, which is used by the %EC, %Ey and %EY format verbs.
+type Era struct {
+	// Start is the first day of the era. Only the year, month, and day
+	// of Start are considered.
+	Start time.Time
+
+	// Offset is the era year number of the year that contains Start,
+	// usually 1.
+	Offset int
+
+	// Name is the name of the era, emitted by %EC.
+	Name string
+
+	// Format is the format string used by %EY to represent the full
+	// alternative year, for instance "%EC%Ey年". When empty, %EY emits
+	// the era name followed by the era year.
+	Format string
+}
+
+// Alternatives provides the alternative representations used by the
+// %E and %O format verb modifiers. The zero value provides the POSIX
+// behavior, where each modified format verb is equivalent to the
+// unmodified verb.
+type Alternatives struct {
+	// Eras is the era table consulted by %EC, %Ey and %EY. A time
+	// belongs to the era with the latest Start that is not after it.
+	// Times preceding every era are formatted as %C, %y, and %Y.
+	Eras []Era
+
+	// Digits holds the alternative representations of the numbers 0,
+	// 1, 2, and so on, used by the %O format verbs. Numbers beyond
+	// the end of the slice are formatted as by the unmodified verb.
+	Digits []string
+
+	// EraDateTime, EraDate and EraTime are the format strings used by
+	// %Ec, %Ex and %EX respectively. When empty, those verbs are
+	// equivalent to %c, %x and %X.
+	EraDateTime, EraDate, EraTime string
+}
+
+// NewWithAlternatives returns a formatter that formats times according
+// to the provided format string, using alt for format verbs having the
+// %E or %O modifier.
+func NewWithAlternatives(format string, alt Alternatives) (*Formatter, error) {
+	locale := posixLocale
+	locale.Alternatives = alt
+	return create(format, &options{locale: &locale})
+}
+
+// modifiedVerbs lists the format verbs that accept each modifier.
+var modifiedVerbs = map[rune]string{
+	'E': "cCxXyY",
+	'O': "deHImMSuUVwWy",
+}
+
+// unmodifiedFormatters maps each format verb that accepts a modifier,
+// other than the locale dependent %c, %x and %X, to the formatter used
+// when no alternative representation is available for a time.
+var unmodifiedFormatters = map[rune]func([]byte, time.Time) []byte{
+	'C': appendCC,
+	'd': appendD,
+	'e': appendE,
+	'H': appendHC,
+	'I': appendIC,
+	'm': appendM,
+	'M': appendMC,
+	'S': appendSC,
+	'u': appendU,
+	'U': appendUC,
+	'V': appendVC,
+	'w': appendW,
+	'W': appendWC,
+	'y': appendY,
+	'Y': appendYC,
+}
+
+// alternativeNumbers maps each format verb that accepts the %O
+// modifier to a function returning the number that verb formats.
+var alternativeNumbers = map[rune]func(time.Time) int{
+	'd': func(t time.Time) int { return t.Day() },
+	'e': func(t time.Time) int { return t.Day() },
+	'H': func(t time.Time) int { return t.Hour() },
+	'I': func(t time.Time) int {
+		hour := t.Hour() % 12
+		if hour == 0 {
+			hour = 12
+		}
+		return hour
+	},
+	'm': func(t time.Time) int { return int(t.Month()) },
+	'M': func(t time.Time) int { return t.Minute() },
+	'S': func(t time.Time) int { return t.Second() },
+	'u': func(t time.Time) int {
+		if wd := t.Weekday(); wd > 0 {
+			return int(wd)
+		}
+		return 7
+	},
+	'U': func(t time.Time) int { return (t.YearDay() + 6 - int(t.Weekday())) / 7 },
+	'V': func(t time.Time) int {
+		_, week := t.ISOWeek()
+		return week
+	},
+	'w': func(t time.Time) int { return int(t.Weekday()) },
+	'W': func(t time.Time) int { return (t.YearDay() + 6 - (int(t.Weekday())+6)%7) / 7 },
+	'y': func(t time.Time) int { return yearOfCentury(t.Year()) },
+}
+
+// makeModifiedFormatter returns the formatter for verb having the
+// provided modifier, which must be listed in modifiedVerbs, or nil when
+// the locale provides no alternative representation and the unmodified
+// verb ought to be used. It also reports whether the result may change
+// within a second, because an alternative format includes %N.
+func makeModifiedFormatter(modifier, verb rune, locale *Locale) (func([]byte, time.Time) []byte, bool, error) {
+	if locale == nil {
+		return nil, false, nil
+	}
+	alt := &locale.Alternatives
+	if modifier == 'O' {
+		if len(alt.Digits) == 0 {
+			return nil, false, nil
+		}
+		return makeDigitsFormatter(verb, alt.Digits), false, nil
+	}
+
+	switch verb {
+	case 'c', 'x', 'X':
+		format := map[rune]string{'c': alt.EraDateTime, 'x': alt.EraDate, 'X': alt.EraTime}[verb]
+		if format == "" {
+			return nil, false, nil
+		}
+		// Compile without the era date and time formats to prevent a
+		// format from referring to itself.
+		nested := *locale
+		nested.EraDateTime, nested.EraDate, nested.EraTime = "", "", ""
+		tf, err := create(format, &options{locale: &nested})
+		if err != nil {
+			return nil, false, fmt.Errorf("cannot compile alternative format %q: %w", format, err)
+		}
+		return tf.Append, tf.subsecond(), nil
+	default:
+		if len(alt.Eras) == 0 {
+			return nil, false, nil
+		}
+		return makeEraFormatter(verb, locale)
+	}
+}
+
+func makeDigitsFormatter(verb rune, digits []string) func([]byte, time.Time) []byte {
+	number := alternativeNumbers[verb]
+	unmodified := unmodifiedFormatters[verb]
+	return func(buf []byte, t time.Time) []byte {
+		if n := number(t); n < len(digits) {
+			return append(buf, digits[n]...)
+		}
+		return unmodified(buf, t)
+	}
+}
+
+func makeEraFormatter(verb rune, locale *Locale) (func([]byte, time.Time) []byte, bool, error) {
+	eras := make([]Era, len(locale.Eras))
+	copy(eras, locale.Eras)
+	sort.SliceStable(eras, func(i, j int) bool { return eraDateBefore(eras[i].Start, eras[j].Start) })
+
+	unmodified := unmodifiedFormatters[verb]
+
+	switch verb {
+	case 'C':
+		return func(buf []byte, t time.Time) []byte {
+			i := findEra(eras, t)
+			if i < 0 {
+				return unmodified(buf, t)
+			}
+			return append(buf, eras[i].Name...)
+		}, false, nil
+	case 'y':
+		return func(buf []byte, t time.Time) []byte {
+			i := findEra(eras, t)
+			if i < 0 {
+				return unmodified(buf, t)
+			}
+			return strconv.AppendInt(buf, int64(eraYear(eras[i], t)), 10)
+		}, false, nil
+	}
+
+	// %EY: Compile each era's format without the era formats to prevent
+	// a format from referring to itself.
+	nested := *locale
+	nested.Eras = make([]Era, len(eras))
+	for i, era := range eras {
+		era.Format = ""
+		nested.Eras[i] = era
+	}
+	formatters := make([]*Formatter, len(eras))
+	var subsecond bool
+	for i, era := range eras {
+		if era.Format == "" {
+			continue
+		}
+		tf, err := create(era.Format, &options{locale: &nested})
+		if err != nil {
+			return nil, false, fmt.Errorf("cannot compile era format %q: %w", era.Format, err)
+		}
+		formatters[i] = tf
+		subsecond = subsecond || tf.subsecond()
+	}
+	return func(buf []byte, t time.Time) []byte {
+		i := findEra(eras, t)
+		if i < 0 {
+			return unmodified(buf, t)
+		}
+		if tf := formatters[i]; tf != nil {
+			return tf.Append(buf, t)
+		}
+		buf = append(buf, eras[i].Name...)
+		return strconv.AppendInt(buf, int64(eraYear(eras[i], t)), 10)
+	}, subsecond, nil
+}
+
+// findEra returns the index of the era to which t belongs, or -1 when t
+// precedes every era. The eras must be sorted by their start dates.
+func findEra(eras []Era, t time.Time) int {
+	for i := len(eras) - 1; i >= 0; i-- {
+		if !eraDateBefore(t, eras[i].Start) {
+			return i
+		}
+	}
+	return -1
+}
+
+// eraDateBefore returns true when the date of a is before the date of
+// b, ignoring the time of day and location of both.
+func eraDateBefore(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	if ay != by {
+		return ay < by
+	}
+	if am != bm {
+		return am < bm
+	}
+	return ad < bd
+}
+
+func eraYear(era Era, t time.Time) int {
+	return t.Year() - era.Start.Year() + era.Offset
+}
@@ -0,0 +1,105 @@
+package gosft
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestModifiersPOSIX(t *testing.T) {
+	when := time.Date(2006, time.January, 2, 3, 4, 5, 12345678, time.UTC)
+
+	for modifier, verbs := range modifiedVerbs {
+		for _, verb := range verbs {
+			modified := "%" + string(modifier) + string(verb)
+			unmodified := "%" + string(verb)
+
+			t.Run(modified, func(t *testing.T) {
+				tf, err := New(modified)
+				ensureError(t, err, nil)
+
+				want, err := New(unmodified)
+				ensureError(t, err, nil)
+
+				if got, want := tf.Format(when), want.Format(when); got != want {
+					t.Errorf("GOT: %q; WANT: %q", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestModifierErrors(t *testing.T) {
+	tests := []struct {
+		format, want string
+	}{
+		{"%Ea", "cannot recognize format verb 'a' with modifier 'E' at index 2"},
+		{"%Od", ""},
+		{"%OY", "cannot recognize format verb 'Y' with modifier 'O' at index 2"},
+		{"%EE", "cannot recognize format verb 'E' with modifier 'E' at index 2"},
+		{"%F %E", "cannot find closing format verb"},
+	}
+
+	for _, c := range tests {
+		t.Run(c.format, func(t *testing.T) {
+			_, err := New(c.format)
+			if c.want == "" {
+				ensureError(t, err, nil)
+			} else {
+				ensureError(t, err, errors.New(c.want))
+			}
+		})
+	}
+}
+
+func TestAlternatives(t *testing.T) {
+	kanji := []string{"〇", "一", "二", "三", "四", "五", "六", "七", "八", "九", "十", "十一", "十二"}
+
+	alt := Alternatives{
+		Eras: []Era{
+			// Deliberately out of order to ensure they are sorted.
+			{Start: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC), Offset: 2, Name: "令和", Format: "%EC%Ey年"},
+			{Start: time.Date(2019, time.May, 1, 0, 0, 0, 0, time.UTC), Offset: 1, Name: "令和", Format: "%EC元年"},
+			{Start: time.Date(1989, time.January, 8, 0, 0, 0, 0, time.UTC), Offset: 1, Name: "平成"},
+		},
+		Digits:      kanji,
+		EraDateTime: "%EY%m月%d日 %H時%M分%S秒",
+		EraDate:     "%EY%m月%d日",
+	}
+
+	tests := []struct {
+		format string
+		when   time.Time
+		want   string
+	}{
+		{"%EC", time.Date(2006, time.January, 2, 3, 4, 5, 0, time.UTC), "平成"},
+		{"%Ey", time.Date(2006, time.January, 2, 3, 4, 5, 0, time.UTC), "18"},
+		{"%EY", time.Date(2006, time.January, 2, 3, 4, 5, 0, time.UTC), "平成18"},
+		{"%EY", time.Date(2019, time.April, 30, 3, 4, 5, 0, time.UTC), "平成31"},
+		{"%EY", time.Date(2019, time.May, 1, 3, 4, 5, 0, time.UTC), "令和元年"},
+		{"%EY", time.Date(2021, time.September, 30, 3, 4, 5, 0, time.UTC), "令和3年"},
+		{"%EY", time.Date(1980, time.January, 2, 3, 4, 5, 0, time.UTC), "1980"},
+		{"%EC %Ey", time.Date(1980, time.January, 2, 3, 4, 5, 0, time.UTC), "19 80"},
+		{"%Ex", time.Date(2021, time.September, 30, 3, 4, 5, 0, time.UTC), "令和3年09月30日"},
+		{"%Ec", time.Date(2021, time.September, 30, 3, 4, 5, 0, time.UTC), "令和3年09月30日 03時04分05秒"},
+		{"%EX", time.Date(2021, time.September, 30, 3, 4, 5, 0, time.UTC), "03:04:05"},
+		{"%Om月%Od日", time.Date(2021, time.September, 30, 3, 4, 5, 0, time.UTC), "九月30日"},
+		{"%OH:%OM", time.Date(2021, time.September, 30, 12, 4, 5, 0, time.UTC), "十二:四"},
+	}
+
+	for _, c := range tests {
+		t.Run(c.format, func(t *testing.T) {
+			tf, err := NewWithAlternatives(c.format, alt)
+			ensureError(t, err, nil)
+
+			if got, want := tf.Format(c.when), c.want; got != want {
+				t.Errorf("GOT: %q; WANT: %q", got, want)
+			}
+		})
+	}
+
+	t.Run("invalid era format", func(t *testing.T) {
+		_, err := NewWithAlternatives("%EY", Alternatives{Eras: []Era{{Name: "bad", Format: "%Q"}}})
+		ensureError(t, err, errors.New("cannot compile era format \"%Q\""))
+	})
+}
@@ -0,0 +1,100 @@
+package gosft
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// CachingFormatter wraps a Formatter, remembering the result of
+// formatting the most recent second in a location. Formatting another
+// time within the same second and location copies that result and
+// formats only the verbs that emit fractional seconds, such as %N,
+// which suits log timestamps where many lines share a second. A single
+// CachingFormatter may safely be used by multiple Go routines
+// simultaneously.
+type CachingFormatter struct {
+	tf        *Formatter
+	segments  [][]instruction // instructions between fractional seconds
+	fractions []instruction   // instructions that emit fractional seconds
+	cache     atomic.Value    // *cachedSecond
+}
+
+// cachedSecond holds the result of formatting a second in a location,
+// without its fractional seconds.
+type cachedSecond struct {
+	unix  int64
+	loc   *time.Location
+	text  []byte
+	marks []int // index within text of each fractional second
+}
+
+// NewCachingFormatter returns a CachingFormatter that formats times in
+// the same manner as tf.
+func NewCachingFormatter(tf *Formatter) *CachingFormatter {
+	cf := &CachingFormatter{tf: tf}
+
+	var segment []instruction
+	for _, in := range tf.program {
+		if in.op == opNanosecond || in.op == opSubsecond {
+			cf.segments = append(cf.segments, segment)
+			cf.fractions = append(cf.fractions, in)
+			segment = nil
+			continue
+		}
+		segment = append(segment, in)
+	}
+	cf.segments = append(cf.segments, segment)
+
+	return cf
+}
+
+// Append will format t in accordance with the format specification of
+// the wrapped Formatter and append the formatted bytes to buf.
+func (cf *CachingFormatter) Append(buf []byte, t time.Time) []byte {
+	if cf.tf.loc != nil {
+		t = t.In(cf.tf.loc)
+	}
+
+	c, _ := cf.cache.Load().(*cachedSecond)
+	if c == nil || c.unix != t.Unix() || c.loc != t.Location() {
+		c = cf.cacheSecond(t)
+	}
+
+	f := fields{nanosecond: t.Nanosecond()}
+	var prev int
+	for i, mark := range c.marks {
+		buf = append(buf, c.text[prev:mark]...)
+		buf = run(buf, cf.fractions[i:i+1], t, &f)
+		prev = mark
+	}
+	return append(buf, c.text[prev:]...)
+}
+
+// Format will format t and return a string in accordance with the
+// format specification of the wrapped Formatter.
+func (cf *CachingFormatter) Format(t time.Time) string {
+	return string(cf.Append(make([]byte, 0, cf.tf.sizeFor(t)), t))
+}
+
+// cacheSecond formats the second of t without its fractional seconds,
+// and stores the result as the most recent second. When multiple Go
+// routines format different seconds at the same time, the most recent
+// second is whichever is stored last.
+func (cf *CachingFormatter) cacheSecond(t time.Time) *cachedSecond {
+	c := &cachedSecond{
+		unix: t.Unix(),
+		loc:  t.Location(),
+		text: make([]byte, 0, cf.tf.sizeFor(t)),
+	}
+
+	f := breakDown(t, cf.tf.needs)
+	for i, segment := range cf.segments {
+		if i > 0 {
+			c.marks = append(c.marks, len(c.text))
+		}
+		c.text = run(c.text, segment, t, &f)
+	}
+
+	cf.cache.Store(c)
+	return c
+}
@@ -0,0 +1,135 @@
+package gosft
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCachingFormatter(t *testing.T) {
+	formats := []string{
+		"%FT%T.%N%K",
+		"%FT%T.%3N%:z %Z",
+		"%N%-N|%_6N|%s.%9N",
+		"%c %j",
+		"",
+	}
+
+	locations := []*time.Location{
+		time.UTC,
+		time.FixedZone("", -7*60*60),
+		time.FixedZone("NPT", 5*60*60),
+	}
+
+	start := time.Date(2021, time.September, 30, 23, 59, 58, 0, time.UTC)
+
+	for _, format := range formats {
+		tf, err := New(format)
+		ensureError(t, err, nil)
+		cf := NewCachingFormatter(tf)
+
+		for i := 0; i < 100; i++ {
+			when := start.Add(time.Duration(i) * 37 * time.Millisecond)
+			if got, want := cf.Format(when), tf.Format(when); got != want {
+				t.Errorf("%q: %v: GOT: %q; WANT: %q", format, when, got, want)
+			}
+			when = when.In(locations[i%3])
+			if got, want := cf.Format(when), tf.Format(when); got != want {
+				t.Errorf("%q: %v: GOT: %q; WANT: %q", format, when, got, want)
+			}
+		}
+	}
+
+	t.Run("layout", func(t *testing.T) {
+		tf, err := NewCompat("2006-01-02 15:04:05.999 MST")
+		ensureError(t, err, nil)
+		cf := NewCachingFormatter(tf)
+
+		for _, nsec := range []int{0, 120000000, 123000000, 999999999} {
+			when := start.Add(time.Duration(nsec))
+			if got, want := cf.Format(when), when.Format("2006-01-02 15:04:05.999 MST"); got != want {
+				t.Errorf("GOT: %q; WANT: %q", got, want)
+			}
+		}
+	})
+
+	t.Run("locale", func(t *testing.T) {
+		// The nested formats of a locale may include fractional
+		// seconds, which must not be cached.
+		locale, _ := LookupLocale("ja_JP")
+		locale.DateTime = "%T.%N"
+		locale.EraDate = "%EY %T.%3N"
+		locale.Eras[len(locale.Eras)-1].Format = "%EC%Ey年 %S.%2N"
+
+		tf, err := NewWithLocale("%c|%Ex|%EY", locale)
+		ensureError(t, err, nil)
+		cf := NewCachingFormatter(tf)
+
+		for _, nsec := range []int{0, 120000000, 123000000, 999999999} {
+			when := start.Add(time.Duration(nsec))
+			if got, want := cf.Format(when), tf.Format(when); got != want {
+				t.Errorf("GOT: %q; WANT: %q", got, want)
+			}
+		}
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		tf, err := New("%F %T.%6N")
+		ensureError(t, err, nil)
+		cf := NewCachingFormatter(tf)
+
+		var wg sync.WaitGroup
+		for g := 0; g < 8; g++ {
+			wg.Add(1)
+			go func(g int) {
+				defer wg.Done()
+				for i := 0; i < 1000; i++ {
+					when := start.Add(time.Duration(g*1000+i) * time.Millisecond)
+					if got, want := cf.Format(when), tf.Format(when); got != want {
+						t.Errorf("GOT: %q; WANT: %q", got, want)
+						return
+					}
+				}
+			}(g)
+		}
+		wg.Wait()
+	})
+
+	t.Run("allocations", func(t *testing.T) {
+		tf, err := New("%FT%T.%3N%:z ")
+		ensureError(t, err, nil)
+		cf := NewCachingFormatter(tf)
+		buf := make([]byte, 0, 64)
+
+		allocs := testing.AllocsPerRun(100, func() {
+			buf = cf.Append(buf[:0], start)
+		})
+		if allocs != 0 {
+			t.Errorf("GOT: %v allocations; WANT: 0", allocs)
+		}
+	})
+}
+
+func BenchmarkCachingFormatter(b *testing.B) {
+	tf, err := New("%a %b %e %T.%6N %Z %Y: ")
+	ensureError(b, err, nil)
+	cf := NewCachingFormatter(tf)
+
+	buf := make([]byte, 0, 64)
+	when := time.Date(2006, time.January, 2, 3, 4, 5, 12345678, time.UTC)
+
+	b.Run("Append", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf = tf.Append(buf[:0], when.Add(time.Duration(i)))
+		}
+	})
+
+	b.Run("CachingFormatter", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf = cf.Append(buf[:0], when.Add(time.Duration(i)))
+		}
+	})
+	_ = buf
+}
@@ -0,0 +1,236 @@
+package gosft
+
+import (
+	"time"
+)
+
+// layoutElements maps each element of a Go reference layout, other than
+// fractional seconds, to the instruction that emits it. See the
+// documentation of the time package constants for a description of
+// each element.
+var layoutElements = map[string]instruction{
+	"January":   {op: opMonthLong},
+	"Jan":       {op: opMonthShort},
+	"1":         {op: opMonthMin},
+	"01":        {op: opMonth},
+	"Monday":    {op: opWeekdayLong},
+	"Mon":       {op: opWeekdayShort},
+	"2":         {op: opDayMin},
+	"_2":        {op: opDaySpace},
+	"02":        {op: opDay},
+	"__2":       {op: opYearDaySpace},
+	"002":       {op: opYearDay},
+	"15":        {op: opHour},
+	"3":         {op: opHour12Min},
+	"03":        {op: opHour12},
+	"4":         {op: opMinuteMin},
+	"04":        {op: opMinute},
+	"5":         {op: opSecondMin},
+	"05":        {op: opSecond},
+	"2006":      {op: opYear},
+	"06":        {op: opYear2},
+	"PM":        {op: opAMPM},
+	"pm":        {op: opAMPMLower},
+	"MST":       {op: opCall, call: appendLayoutZoneName},
+	"Z0700":     {op: opCall, call: makeOffsetFormatter(true, false, true, false)},
+	"Z070000":   {op: opCall, call: makeOffsetFormatter(true, false, true, true)},
+	"Z07":       {op: opCall, call: makeOffsetFormatter(true, false, false, false)},
+	"Z07:00":    {op: opCall, call: makeOffsetFormatter(true, true, true, false)},
+	"Z07:00:00": {op: opCall, call: makeOffsetFormatter(true, true, true, true)},
+	"-0700":     {op: opCall, call: makeOffsetFormatter(false, false, true, false)},
+	"-070000":   {op: opCall, call: makeOffsetFormatter(false, false, true, true)},
+	"-07":       {op: opCall, call: makeOffsetFormatter(false, false, false, false)},
+	"-07:00":    {op: opCall, call: makeOffsetFormatter(false, true, true, false)},
+	"-07:00:00": {op: opCall, call: makeOffsetFormatter(false, true, true, true)},
+}
+
+// compileLayout returns the program that emits each element of the Go
+// reference layout, in the same manner as time.Time.Format. Text that
+// is not a layout element is emitted verbatim.
+func compileLayout(layout string) []instruction {
+	var program []instruction
+
+	var literal int // index of the first byte of pending literal text
+
+	for i := 0; i < len(layout); {
+		element, in := nextLayoutElement(layout, i)
+		if element == "" {
+			i++
+			continue
+		}
+		if literal < i {
+			program = appendText(program, layout[literal:i])
+		}
+		program = append(program, in)
+		i += len(element)
+		literal = i
+	}
+
+	if literal < len(layout) {
+		program = appendText(program, layout[literal:])
+	}
+
+	return program
+}
+
+// nextLayoutElement returns the layout element that begins at index i of
+// layout and the instruction that emits it, or the empty string when
+// literal text begins at i. It recognizes elements using the same
+// precedence as the time package.
+func nextLayoutElement(layout string, i int) (string, instruction) {
+	rest := layout[i:]
+	var element string
+
+	switch c := rest[0]; c {
+	case 'J': // January, Jan
+		if element = longestPrefix(rest, "January", "Jan"); element == "Jan" && startsWithLowerCase(rest[3:]) {
+			element = ""
+		}
+	case 'M': // Monday, Mon, MST
+		if element = longestPrefix(rest, "Monday", "Mon", "MST"); element == "Mon" && startsWithLowerCase(rest[3:]) {
+			element = ""
+		}
+	case '0': // 01, 02, 03, 04, 05, 06, 002
+		if len(rest) >= 2 && '1' <= rest[1] && rest[1] <= '6' {
+			element = rest[:2]
+		} else {
+			element = longestPrefix(rest, "002")
+		}
+	case '1': // 15, 1
+		element = longestPrefix(rest, "15", "1")
+	case '2': // 2006, 2
+		element = longestPrefix(rest, "2006", "2")
+	case '_': // _2, __2; _2006 is a literal _ followed by 2006
+		if len(rest) >= 5 && rest[1:5] == "2006" {
+			return "", instruction{}
+		}
+		element = longestPrefix(rest, "_2", "__2")
+	case '3', '4', '5':
+		element = rest[:1]
+	case 'P': // PM
+		element = longestPrefix(rest, "PM")
+	case 'p': // pm
+		element = longestPrefix(rest, "pm")
+	case '-': // -070000, -07:00:00, -0700, -07:00, -07
+		element = longestPrefix(rest, "-070000", "-07:00:00", "-0700", "-07:00", "-07")
+	case 'Z': // Z070000, Z07:00:00, Z0700, Z07:00, Z07
+		element = longestPrefix(rest, "Z070000", "Z07:00:00", "Z0700", "Z07:00", "Z07")
+	case '.', ',': // ,000, or .000, or ,999, or .999 - repeated digits for fractional seconds.
+		if len(rest) >= 2 && (rest[1] == '0' || rest[1] == '9') {
+			digit := rest[1]
+			j := 1
+			for j < len(rest) && rest[j] == digit {
+				j++
+			}
+			// String of digits must end here - only fractional second
+			// if all digits are the same.
+			if j < len(rest) && '0' <= rest[j] && rest[j] <= '9' {
+				return "", instruction{}
+			}
+			return rest[:j], instruction{op: opSubsecond, call: makeLayoutFractionFormatter(c, j-1, digit == '9')}
+		}
+	}
+
+	if element == "" {
+		return "", instruction{}
+	}
+	return element, layoutElements[element]
+}
+
+// startsWithLowerCase returns true when s begins with a lowercase
+// letter, which prevents a word such as "Monthly" from being mistaken
+// for a layout element.
+func startsWithLowerCase(s string) bool {
+	return len(s) > 0 && 'a' <= s[0] && s[0] <= 'z'
+}
+
+// longestPrefix returns the first of the candidates that is a prefix of
+// s, or the empty string when none are.
+func longestPrefix(s string, candidates ...string) string {
+	for _, candidate := range candidates {
+		if len(s) >= len(candidate) && s[:len(candidate)] == candidate {
+			return candidate
+		}
+	}
+	return ""
+}
+
+func appendLayoutZoneName(buf []byte, t time.Time) []byte {
+	// When the time zone has no abbreviation, the time package emits
+	// the offset in the -0700 format.
+	name, offset := t.Zone()
+	if name != "" {
+		return append(buf, name...)
+	}
+	zone := offset / 60
+	if zone < 0 {
+		buf = append(buf, '-')
+		zone = -zone
+	} else {
+		buf = append(buf, '+')
+	}
+	buf = append2DigitsZero(buf, zone/60)
+	return append2DigitsZero(buf, zone%60)
+}
+
+// makeOffsetFormatter returns a formatter that emits the time zone offset
+// as a sign followed by the hours, and optionally the minutes and
+// seconds, separated by colons when colon is true. When utc is true, a
+// zero offset is emitted as Z, as required by ISO 8601.
+func makeOffsetFormatter(utc, colon, minutes, seconds bool) func([]byte, time.Time) []byte {
+	return func(buf []byte, t time.Time) []byte {
+		_, offset := t.Zone()
+		if utc && offset == 0 {
+			return append(buf, 'Z')
+		}
+		if offset < 0 {
+			buf = append(buf, '-')
+			offset = -offset
+		} else {
+			buf = append(buf, '+')
+		}
+		buf = append2DigitsZero(buf, offset/3600)
+		if minutes {
+			if colon {
+				buf = append(buf, ':')
+			}
+			buf = append2DigitsZero(buf, offset/60%60)
+		}
+		if seconds {
+			if colon {
+				buf = append(buf, ':')
+			}
+			buf = append2DigitsZero(buf, offset%60)
+		}
+		return buf
+	}
+}
+
+// makeLayoutFractionFormatter returns a formatter that emits the
+// separator followed by the provided number of digits of fractional
+// seconds, of which there are at most 9. When trim is true, trailing
+// zeros are removed, and when no digits remain the separator is also
+// omitted.
+func makeLayoutFractionFormatter(separator byte, precision int, trim bool) func([]byte, time.Time) []byte {
+	if precision > 9 {
+		precision = 9
+	}
+	divisor := 1
+	for i := precision; i < 9; i++ {
+		divisor *= 10
+	}
+	return func(buf []byte, t time.Time) []byte {
+		start := len(buf)
+		buf = append(buf, separator)
+		buf = appendFraction(buf, t.Nanosecond()/divisor, precision)
+		if trim {
+			for len(buf) > start+1 && buf[len(buf)-1] == '0' {
+				buf = buf[:len(buf)-1]
+			}
+			if len(buf) == start+1 {
+				buf = buf[:start]
+			}
+		}
+		return buf
+	}
+}
@@ -0,0 +1,109 @@
+package gosft
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+)
+
+func TestCompatLayouts(t *testing.T) {
+	layouts := []string{
+		time.ANSIC,
+		time.UnixDate,
+		time.RubyDate,
+		time.RFC822,
+		time.RFC822Z,
+		time.RFC850,
+		time.RFC1123,
+		time.RFC1123Z,
+		time.RFC3339,
+		time.RFC3339Nano,
+		time.Kitchen,
+		time.Stamp,
+		time.StampMilli,
+		time.StampMicro,
+		time.StampNano,
+		"2006-01-02 15:04:05",
+		"2006-01-02",
+		"15:04:05",
+		"2006-01-02 15:04:05.000 MST",
+		"2006-01-02T15:04:05.999999999Z07:00",
+		"Monday, January 2, 2006 at 3:4:5 pm",
+		"Mon Jan _2 __2 002 15:04:05",
+		"20060102150405",
+		"_2006 _2",
+		"15:04:05,000 -07",
+		"15:04:05,999 -07:00:00",
+		"15:04:05.9 Z07",
+		"15:04:05.00 Z070000",
+		"15:04:05.0000 Z07:00:00",
+		"05.0000000000",
+		"05.99999999999",
+		"05,000000000000 2006",
+		"15:04:05.99999 -070000",
+		"Date: 1/2/06 4:05 (MST)",
+		"version 1.2.3", // fractions must not be followed by other digits
+		".01 .0 .",
+		"",
+	}
+
+	times := []time.Time{
+		time.Date(2006, time.January, 2, 15, 4, 5, 123456789, time.UTC),
+		time.Date(2009, time.February, 5, 5, 0, 57, 12345600, time.UTC),
+		time.Date(2021, time.September, 30, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, time.December, 31, 12, 59, 59, 100000000, time.FixedZone("", -7*60*60)),
+		time.Date(2021, time.March, 14, 1, 5, 9, 120000000, time.FixedZone("NPT", 5*60*60+45*60)),
+		time.Date(2021, time.March, 14, 1, 5, 9, 120000000, time.FixedZone("", -(9*60*60+30*60))),
+		time.Date(1880, time.April, 1, 10, 11, 12, 0, time.FixedZone("LMT", -(4*60*60+56*60+2))),
+	}
+
+	for _, layout := range layouts {
+		tf, err := NewCompat(layout)
+		ensureError(t, err, nil)
+
+		for _, when := range times {
+			t.Run(layout+"/"+when.String(), func(t *testing.T) {
+				if got, want := tf.Format(when), when.Format(layout); got != want {
+					t.Errorf("GOT: %q; WANT: %q", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestCompatRandom(t *testing.T) {
+	// Build random layouts from layout elements and literal text, and
+	// ensure the result matches the standard library for random times.
+	elements := []string{
+		"January", "Jan", "1", "01", "Monday", "Mon", "2", "_2", "02", "__2", "002",
+		"15", "3", "03", "4", "04", "5", "05", "2006", "06", "PM", "pm", "MST",
+		"Z0700", "Z070000", "Z07", "Z07:00", "Z07:00:00",
+		"-0700", "-070000", "-07", "-07:00", "-07:00:00",
+		".000", ".999", ",00", ",9", " ", ":", "-", "_", ".", "T", "x",
+	}
+
+	zones := []*time.Location{
+		time.UTC,
+		time.FixedZone("", 0),
+		time.FixedZone("EST", -5*60*60),
+		time.FixedZone("", 5*60*60+30*60),
+		time.FixedZone("", -(3*60*60 + 25*60 + 7)),
+	}
+
+	rng := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 1000; i++ {
+		var layout string
+		for j := rng.Intn(8); j >= 0; j-- {
+			layout += elements[rng.Intn(len(elements))]
+		}
+
+		tf, err := NewCompat(layout)
+		ensureError(t, err, nil)
+
+		when := time.Unix(rng.Int63n(4102444800), rng.Int63n(1e9)).In(zones[rng.Intn(len(zones))])
+		if got, want := tf.Format(when), when.Format(layout); got != want {
+			t.Errorf("%q: %v: GOT: %q; WANT: %q", layout, when, got, want)
+		}
+	}
+}
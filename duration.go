@@ -0,0 +1,355 @@
+package gosft
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DurationFormatter will format time.Duration values in accordance with
+// their configured format specification. A single DurationFormatter may
+// safely be used by multiple Go routines simultaneously.
+type DurationFormatter struct {
+	program []durationInstruction
+	largest durationOpcode // largest unit formatted by the program
+	signed  bool           // whether the program has a sign verb
+	size    int
+}
+
+// durationOpcode identifies the operation performed by a duration
+// instruction.
+type durationOpcode uint8
+
+const (
+	durationText         durationOpcode = iota // append the text of the instruction
+	durationSign                               // %+
+	durationFraction                           // %N
+	durationSeconds                            // %S
+	durationMinutes                            // %M
+	durationHours                              // %H
+	durationDays                               // %d
+	durationTotalNanos                         // %{nanoseconds}
+	durationTotalMicros                        // %{microseconds}
+	durationTotalMillis                        // %{milliseconds}
+	durationTotalSeconds                       // %{seconds}
+	durationTotalMinutes                       // %{minutes}
+	durationTotalHours                         // %{hours}
+	durationTotalDays                          // %{days}
+)
+
+// durationInstruction is a single step of a compiled duration program.
+type durationInstruction struct {
+	op       durationOpcode
+	text     string // text for durationText
+	width    int    // field width, or precision for durationFraction
+	pad      byte   // padding character, or zero for none
+	suppress bool   // omit a leading zero value and the text following it
+}
+
+// durationVerbs maps each duration format verb to its opcode.
+var durationVerbs = map[rune]durationOpcode{
+	'+': durationSign,
+	'N': durationFraction,
+	'S': durationSeconds,
+	'M': durationMinutes,
+	'H': durationHours,
+	'd': durationDays,
+}
+
+// durationTotals maps the name of each duration format verb written
+// between braces, which formats the duration as a whole number of some
+// unit, to its opcode.
+var durationTotals = map[string]durationOpcode{
+	"nanoseconds":  durationTotalNanos,
+	"microseconds": durationTotalMicros,
+	"milliseconds": durationTotalMillis,
+	"seconds":      durationTotalSeconds,
+	"minutes":      durationTotalMinutes,
+	"hours":        durationTotalHours,
+	"days":         durationTotalDays,
+}
+
+// durationWidths maps each unit of a duration to its default field
+// width.
+var durationWidths = map[durationOpcode]int{
+	durationFraction: 9,
+	durationSeconds:  2,
+	durationMinutes:  2,
+	durationHours:    2,
+}
+
+// NewDurationFormatter returns a formatter that formats durations
+// according to the provided format string. The %d, %H, %M and %S verbs
+// format the days, hours, minutes and seconds of a duration, except that
+// the largest of those units in the format string includes the whole
+// duration, so that %H:%M:%S formats 36 hours as 36:00:00. The field
+// width of %N, which formats the fractional seconds, is its precision.
+// The %{days}, %{hours}, %{minutes}, %{seconds}, %{milliseconds},
+// %{microseconds} and %{nanoseconds} verbs format the duration as a
+// whole number of that unit, and count as the largest unit like their
+// counterparts, so that "%{hours}h %Mm" formats 90 minutes as "1h 30m".
+//
+// A negative duration is formatted with a '-' preceding its first
+// number, unless the format string has the %+ verb, which formats the
+// sign of the duration as either '+' or '-'. The '#' flag omits a verb
+// whose value is zero, along with the text that follows it up to the
+// next verb, when each preceding verb has been omitted, so that
+// "%#dd %#-Hh %-Mm" formats 90 minutes as "1h 30m".
+func NewDurationFormatter(format string) (*DurationFormatter, error) {
+	program, err := compileDuration(format)
+	if err != nil {
+		return nil, err
+	}
+
+	df := &DurationFormatter{program: program, largest: durationSeconds}
+	for _, in := range program {
+		switch in.op {
+		case durationSign:
+			df.signed = true
+		case durationSeconds, durationMinutes, durationHours, durationDays:
+			if in.op > df.largest {
+				df.largest = in.op
+			}
+		case durationTotalSeconds, durationTotalMinutes, durationTotalHours, durationTotalDays:
+			// A total includes the whole duration, so the smaller
+			// units are the remainder of it.
+			if unit := in.op - durationTotalSeconds + durationSeconds; unit > df.largest {
+				df.largest = unit
+			}
+		}
+	}
+
+	// The duration having the greatest magnitude is the longest.
+	df.size = len(df.Append(nil, math.MinInt64))
+
+	return df, nil
+}
+
+// compileDuration returns the instructions that emit each verb of
+// format and the text between them.
+func compileDuration(format string) ([]durationInstruction, error) {
+	var program []durationInstruction
+	var buf []byte
+	var foundPercent bool
+	var percent int // index of the '%' beginning the current verb
+	var flags []byte
+	var width int
+	var skip int // index following a verb written between braces
+
+	for ri, rune := range format {
+		if ri < skip {
+			continue
+		}
+		if !foundPercent {
+			if rune == '%' {
+				foundPercent = true
+				percent = ri
+				if len(buf) > 0 {
+					program = append(program, durationInstruction{op: durationText, text: string(buf)})
+					buf = nil
+				}
+			} else {
+				buf = appendRune(buf, rune)
+			}
+			continue
+		}
+		if width == 0 && strings.ContainsRune(formatFlags, rune) {
+			flags = append(flags, byte(rune))
+			continue
+		}
+		if rune >= '0' && rune <= '9' {
+			if width = width*10 + int(rune-'0'); width > maxWidth {
+				return nil, fmt.Errorf("cannot use field width greater than %d at index %d", maxWidth, ri)
+			}
+			continue
+		}
+
+		var text string
+		op, ok := durationVerbs[rune]
+		switch rune {
+		case '{':
+			end := strings.IndexByte(format[ri:], '}')
+			if end < 0 {
+				return nil, &TrailingPercentError{Format: format, Offset: percent}
+			}
+			name := format[ri+1 : ri+end]
+			skip = ri + end + 1
+			if op, ok = durationTotals[name]; !ok {
+				return nil, &UnknownVerbError{Format: format, Offset: ri, Verb: rune, Name: name}
+			}
+		case '%', 'n', 't':
+			text, ok = verbTexts[rune]
+		}
+		if !ok {
+			return nil, &UnknownVerbError{Format: format, Offset: ri, Verb: rune}
+		}
+
+		if text != "" {
+			buf = append(buf, text...)
+		} else {
+			in := durationInstruction{op: op, width: durationWidths[op], pad: '0'}
+			for _, flag := range flags {
+				switch flag {
+				case '-':
+					in.pad = 0
+				case '_':
+					in.pad = ' '
+				case '0':
+					in.pad = '0'
+				case '#':
+					in.suppress = true
+				}
+			}
+			if width > 0 {
+				in.width = width
+			}
+			if op == durationFraction && in.width > 9 {
+				in.width = 9
+			}
+			program = append(program, in)
+		}
+
+		foundPercent = false
+		flags = nil
+		width = 0
+	}
+
+	if foundPercent {
+		return nil, &TrailingPercentError{Format: format, Offset: percent}
+	}
+
+	if len(buf) > 0 {
+		program = append(program, durationInstruction{op: durationText, text: string(buf)})
+	}
+
+	return program, nil
+}
+
+// Append will format d in accordance with the format specification of
+// the DurationFormatter and append the formatted bytes to buf.
+func (df *DurationFormatter) Append(buf []byte, d time.Duration) []byte {
+	negative := d < 0
+	magnitude := uint64(d)
+	if negative {
+		magnitude = -magnitude
+	}
+	seconds := magnitude / 1e9
+
+	signed := df.signed || !negative
+	leading := true // whether each preceding verb has been omitted
+
+	for i := 0; i < len(df.program); i++ {
+		in := &df.program[i]
+
+		var v uint64
+		switch in.op {
+		case durationText:
+			buf = append(buf, in.text...)
+			continue
+		case durationSign:
+			if negative {
+				buf = append(buf, '-')
+			} else {
+				buf = append(buf, '+')
+			}
+			continue
+		case durationFraction:
+			v = magnitude % 1e9
+		case durationSeconds:
+			v = seconds
+		case durationMinutes:
+			v = seconds / 60
+		case durationHours:
+			v = seconds / (60 * 60)
+		case durationDays:
+			v = seconds / secondsPerDay
+		case durationTotalNanos:
+			v = magnitude
+		case durationTotalMicros:
+			v = magnitude / 1e3
+		case durationTotalMillis:
+			v = magnitude / 1e6
+		case durationTotalSeconds:
+			v = seconds
+		case durationTotalMinutes:
+			v = seconds / 60
+		case durationTotalHours:
+			v = seconds / (60 * 60)
+		case durationTotalDays:
+			v = seconds / secondsPerDay
+		}
+
+		// Each unit smaller than the largest unit of the format is
+		// the remainder of the next larger unit.
+		if in.op < df.largest {
+			switch in.op {
+			case durationSeconds, durationMinutes:
+				v %= 60
+			case durationHours:
+				v %= 24
+			}
+		}
+
+		if in.suppress && leading && v == 0 {
+			if i+1 < len(df.program) && df.program[i+1].op == durationText {
+				i++ // also omit the text following the verb
+			}
+			continue
+		}
+		leading = false
+
+		if !signed {
+			buf = append(buf, '-')
+			signed = true
+		}
+		if in.op == durationFraction {
+			buf = appendDurationFraction(buf, int(v), in.width, in.pad == 0)
+		} else {
+			buf = appendDurationField(buf, v, in.width, in.pad)
+		}
+	}
+
+	return buf
+}
+
+// Format will format d and return a string in accordance with the
+// format specification of the DurationFormatter.
+func (df *DurationFormatter) Format(d time.Duration) string {
+	return string(df.Append(make([]byte, 0, df.size), d))
+}
+
+// appendDurationField appends v padded with pad to width characters.
+func appendDurationField(buf []byte, v uint64, width int, pad byte) []byte {
+	if v < 100 && width == 2 && pad == '0' {
+		return append2DigitsZero(buf, int(v))
+	}
+	start := len(buf)
+	buf = strconv.AppendUint(buf, v, 10)
+	if pad != 0 {
+		buf = padField(buf, start, width, pad, true)
+	}
+	return buf
+}
+
+// appendDurationFraction appends the first precision digits of the
+// fractional seconds nsec, trimming trailing zeros when trim is true,
+// although at least one digit remains.
+func appendDurationFraction(buf []byte, nsec, precision int, trim bool) []byte {
+	start := len(buf)
+	if precision == 9 {
+		buf = append9DigitsZero(buf, nsec)
+	} else {
+		for i := precision; i < 9; i++ {
+			nsec /= 10
+		}
+		buf = appendFraction(buf, nsec, precision)
+	}
+	if trim {
+		for len(buf) > start+1 && buf[len(buf)-1] == '0' {
+			buf = buf[:len(buf)-1]
+		}
+	}
+	return buf
+}
@@ -0,0 +1,118 @@
+package gosft
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDurationFormatter(t *testing.T) {
+	const runtime = 49*time.Hour + 2*time.Minute + 3*time.Second + 456789123
+
+	tests := []struct {
+		format string
+		d      time.Duration
+		want   string
+	}{
+		// The largest unit of the format includes the whole duration.
+		{"%H:%M:%S", runtime, "49:02:03"},
+		{"%dd %Hh", runtime, "2d 01h"},
+		{"%dd %-Hh %-Mm %-Ss", runtime, "2d 1h 2m 3s"},
+		{"%M:%S.%3N", runtime, "2942:03.456"},
+		{"%S.%N", runtime, "176523.456789123"},
+		{"%S.%-6N", 1500 * time.Millisecond, "01.5"},
+		{"%S", 0, "00"},
+		{"%_3H|%5M|%-d", 3*time.Hour + 4*time.Minute, "  3|00004|0"},
+
+		// The totals include the whole duration, and the smaller
+		// units following them are the remainder.
+		{"%{days} %{hours} %{minutes} %{seconds}", runtime, "2 49 2942 176523"},
+		{"%{milliseconds} %{microseconds} %{nanoseconds}", runtime, "176523456 176523456789 176523456789123"},
+		{"%{hours}h %M:%S", runtime, "49h 02:03"},
+		{"%{hours}h %-Mm", 90 * time.Minute, "1h 30m"},
+		{"%{hours}h %-Mm", -36 * time.Hour, "-36h 0m"},
+		{"%{minutes}m %-Ss", -90 * time.Second, "-1m 30s"},
+		{"%{days}d %H:%M", runtime, "2d 01:02"},
+		{"%{seconds}s %M", runtime, "176523s 2942"},
+		{"%8{seconds}", 90 * time.Second, "00000090"},
+
+		// Signs.
+		{"%H:%M:%S", -runtime, "-49:02:03"},
+		{"took %H:%M", -90 * time.Second, "took -00:01"},
+		{"%+%H:%M", runtime, "+49:02"},
+		{"%+%H:%M", -runtime, "-49:02"},
+		{"%H:%M (%+)", -runtime, "49:02 (-)"},
+		{"%{seconds}.%3N", -1500 * time.Millisecond, "-1.500"},
+
+		// Zero suppression.
+		{"%#dd %#-Hh %#-Mm %Ss", 90 * time.Minute, "1h 30m 00s"},
+		{"%#dd %#Hh %#Mm %-Ss", 3 * time.Second, "3s"},
+		{"%#dd %#Hh %#Mm %-Ss", 24*time.Hour + 3*time.Second, "1d 00h 00m 3s"},
+		{"%#dd %#-Hh %#-Mm %-Ss", -90 * time.Minute, "-1h 30m 0s"},
+		{"%#-dd %#-Hh", 0, ""},
+
+		// Literal text.
+		{"%%%n%t%H", time.Hour, "%\n\t01"},
+	}
+
+	for _, c := range tests {
+		t.Run(c.format, func(t *testing.T) {
+			df, err := NewDurationFormatter(c.format)
+			ensureError(t, err, nil)
+			if got, want := df.Format(c.d), c.want; got != want {
+				t.Errorf("GOT: %q; WANT: %q", got, want)
+			}
+		})
+	}
+
+	t.Run("extremes", func(t *testing.T) {
+		df, err := NewDurationFormatter("%dd %H:%M:%S.%N")
+		ensureError(t, err, nil)
+
+		for _, c := range []struct {
+			d    time.Duration
+			want string
+		}{
+			{math.MaxInt64, "106751d 23:47:16.854775807"},
+			{math.MinInt64, "-106751d 23:47:16.854775808"},
+		} {
+			if got, want := df.Format(c.d), c.want; got != want {
+				t.Errorf("GOT: %q; WANT: %q", got, want)
+			}
+			if got, want := df.size, len(c.want); got < want {
+				t.Errorf("GOT: %d; WANT: >= %d", got, want)
+			}
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			format, want string
+		}{
+			{"%H:%Y", "cannot recognize format verb 'Y' at index 4"},
+			{"%H:%{weeks}", `cannot recognize extended format verb "weeks" at index 4`},
+			{"%H:%{hours", "cannot find closing format verb at index 3"},
+			{"%H:%", "cannot find closing format verb at index 3"},
+			{"%2000H", "cannot use field width greater than 1024 at index 4"},
+		}
+
+		for _, c := range tests {
+			_, err := NewDurationFormatter(c.format)
+			ensureError(t, err, errors.New(c.want))
+		}
+	})
+}
+
+func BenchmarkDurationFormatter(b *testing.B) {
+	df, err := NewDurationFormatter("%H:%M:%S.%3N")
+	ensureError(b, err, nil)
+	d := 49*time.Hour + 2*time.Minute + 3*time.Second + 456789123
+
+	b.ReportAllocs()
+	var buf []byte
+	for i := 0; i < b.N; i++ {
+		buf = df.Append(buf[:0], d)
+	}
+	_ = buf
+}
@@ -15,7 +15,7 @@ func ensureError(tb testing.TB, got, want error) {
 	} else if want == nil {
 		tb.Fatalf("GOT: %T(%q); WANT: %v", got, got.Error(), want)
 	} else {
-		var target error
+		var target interface{ Error() string }
 		if ok := errors.As(got, &target); !ok {
 			tb.Fatalf("GOT: %T(%q); WANT: %T(%q)", got, got.Error(), want, want.Error())
 		}
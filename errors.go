@@ -0,0 +1,66 @@
+package gosft
+
+import (
+	"fmt"
+	"strings"
+)
+
+// UnknownVerbError is returned when a format string contains a format
+// verb that is not recognized, including an extended verb, such as
+// %{fiscal_year}, when extended verbs are not enabled.
+type UnknownVerbError struct {
+	Format string // Format is the format string.
+	Offset int    // Offset is the byte offset of the verb within Format.
+	Verb   rune   // Verb is the unrecognized format verb, or '{' for an extended verb.
+	Name   string // Name is the name of an unrecognized extended verb.
+}
+
+func (e *UnknownVerbError) Error() string {
+	if e.Verb == '{' {
+		return fmt.Sprintf("cannot recognize extended format verb %q at index %d", e.Name, e.Offset) + caret(e.Format, e.Offset)
+	}
+	return fmt.Sprintf("cannot recognize format verb %q at index %d", e.Verb, e.Offset) + caret(e.Format, e.Offset)
+}
+
+// TrailingPercentError is returned when a format string ends with a '%'
+// character that does not begin a complete format verb.
+type TrailingPercentError struct {
+	Format string // Format is the format string.
+	Offset int    // Offset is the byte offset of the '%' within Format.
+}
+
+func (e *TrailingPercentError) Error() string {
+	return fmt.Sprintf("cannot find closing format verb at index %d", e.Offset) + caret(e.Format, e.Offset)
+}
+
+// UnsupportedModifierError is returned when a format string contains a
+// format verb having a modifier it does not accept, such as %Ea.
+type UnsupportedModifierError struct {
+	Format   string // Format is the format string.
+	Offset   int    // Offset is the byte offset of the verb within Format.
+	Modifier rune   // Modifier is either 'E' or 'O'.
+	Verb     rune   // Verb is the modified format verb.
+}
+
+func (e *UnsupportedModifierError) Error() string {
+	return fmt.Sprintf("cannot recognize format verb %q with modifier %q at index %d", e.Verb, e.Modifier, e.Offset) + caret(e.Format, e.Offset)
+}
+
+// caret returns the format string on its own line, followed by a line
+// with a caret under the character at offset. Tabs preceding offset are
+// preserved so the caret remains aligned.
+func caret(format string, offset int) string {
+	var b strings.Builder
+	b.WriteString("\n\t")
+	b.WriteString(format)
+	b.WriteString("\n\t")
+	for _, r := range format[:offset] {
+		if r == '\t' {
+			b.WriteByte('\t')
+		} else {
+			b.WriteByte(' ')
+		}
+	}
+	b.WriteByte('^')
+	return b.String()
+}
@@ -0,0 +1,84 @@
+package gosft
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestErrorTypes(t *testing.T) {
+	t.Run("unknown verb", func(t *testing.T) {
+		for _, compile := range []func(string) error{
+			func(format string) error { _, err := New(format); return err },
+			func(format string) error { _, err := NewParser(format); return err },
+		} {
+			err := compile("%F %Q")
+
+			var target *UnknownVerbError
+			if !errors.As(err, &target) {
+				t.Fatalf("GOT: %#v; WANT: %T", err, target)
+			}
+			if target.Format != "%F %Q" || target.Offset != 4 || target.Verb != 'Q' {
+				t.Errorf("GOT: %#v", target)
+			}
+			if got, want := err.Error(), "cannot recognize format verb 'Q' at index 4\n\t%F %Q\n\t    ^"; got != want {
+				t.Errorf("GOT: %q; WANT: %q", got, want)
+			}
+		}
+	})
+
+	t.Run("trailing percent", func(t *testing.T) {
+		for _, compile := range []func(string) error{
+			func(format string) error { _, err := New(format); return err },
+			func(format string) error { _, err := NewParser(format); return err },
+			func(format string) error { _, err := ToGoLayout(format); return err },
+		} {
+			err := compile("%F\t%-")
+
+			var target *TrailingPercentError
+			if !errors.As(err, &target) {
+				t.Fatalf("GOT: %#v; WANT: %T", err, target)
+			}
+			if target.Format != "%F\t%-" || target.Offset != 3 {
+				t.Errorf("GOT: %#v", target)
+			}
+			if got, want := err.Error(), "cannot find closing format verb at index 3\n\t%F\t%-\n\t  \t^"; got != want {
+				t.Errorf("GOT: %q; WANT: %q", got, want)
+			}
+		}
+	})
+
+	t.Run("unsupported modifier", func(t *testing.T) {
+		for _, compile := range []func(string) error{
+			func(format string) error { _, err := New(format); return err },
+			func(format string) error { _, err := NewParser(format); return err },
+		} {
+			err := compile("Jahr: %OY")
+
+			var target *UnsupportedModifierError
+			if !errors.As(err, &target) {
+				t.Fatalf("GOT: %#v; WANT: %T", err, target)
+			}
+			if target.Format != "Jahr: %OY" || target.Offset != 8 || target.Modifier != 'O' || target.Verb != 'Y' {
+				t.Errorf("GOT: %#v", target)
+			}
+			if got, want := err.Error(), "cannot recognize format verb 'Y' with modifier 'O' at index 8\n\tJahr: %OY\n\t        ^"; got != want {
+				t.Errorf("GOT: %q; WANT: %q", got, want)
+			}
+		}
+	})
+
+	t.Run("nested format", func(t *testing.T) {
+		locale, _ := LookupLocale("de_DE")
+		locale.Date = "%d.%m.%Q"
+
+		_, err := NewWithLocale("%x", locale)
+
+		var target *UnknownVerbError
+		if !errors.As(err, &target) {
+			t.Fatalf("GOT: %#v; WANT: %T", err, target)
+		}
+		if target.Format != "%d.%m.%Q" || target.Offset != 7 {
+			t.Errorf("GOT: %#v", target)
+		}
+	})
+}
@@ -0,0 +1,303 @@
+package gosft
+
+import (
+	"strconv"
+	"time"
+)
+
+// extendedVerb describes an extended format verb, enabled by the
+// WithExtendedVerbs option.
+type extendedVerb struct {
+	make      func(o *options, precision int) func([]byte, time.Time) []byte
+	width     int  // default field width of a numeric verb, or zero
+	precision int  // default precision of a verb whose field width is its precision, or zero
+	subsecond bool // whether the result may change within a second
+
+	// parse, when not nil, parses the result of the verb.
+	parse func(*parseState) error
+}
+
+// extendedVerbs maps the name of each extended format verb, which is
+// written between braces, such as %{half}, to its description.
+var extendedVerbs = map[string]extendedVerb{
+	"half":           {make: func(*options, int) func([]byte, time.Time) []byte { return appendHalf }, width: 1},
+	"fiscal_year":    {make: makeFiscalYearFormatter, width: 4},
+	"fiscal_quarter": {make: makeFiscalQuarterFormatter, width: 1},
+	"jdn":            {make: makeJulianDayFormatter(julianEpoch), width: 1},
+	"jd":             {make: makeJulianDateFormatter(julianEpoch), precision: 6, subsecond: true},
+	"mjdn":           {make: makeJulianDayFormatter(modifiedJulianEpoch), width: 1},
+	"mjd":            {make: makeJulianDateFormatter(modifiedJulianEpoch), precision: 6, subsecond: true},
+	"epoch":          {make: makeEpochFormatter(0), precision: 9, subsecond: true, parse: makeEpochParser(0)},
+	"epoch_ms":       {make: makeEpochFormatter(3), width: 1, subsecond: true, parse: makeEpochParser(3)},
+	"epoch_us":       {make: makeEpochFormatter(6), width: 1, subsecond: true, parse: makeEpochParser(6)},
+	"epoch_ns":       {make: makeEpochFormatter(9), width: 1, subsecond: true, parse: makeEpochParser(9)},
+	"expanded_year":  {make: makeExpandedYearFormatter, precision: 4, parse: parseExpandedYear},
+	"era":            {make: makeEraNameFormatter("BC", "AD")},
+	"common_era":     {make: makeEraNameFormatter("BCE", "CE")},
+	"era_year":       {make: func(*options, int) func([]byte, time.Time) []byte { return appendEraYear }, width: 1},
+}
+
+func appendHalf(buf []byte, t time.Time) []byte {
+	// %{half}  The half of the year (range 1 to 2).
+	return append(buf, byte((t.Month()+5)/6+'0'))
+}
+
+// fiscalMonth returns the zero based month of the fiscal year beginning
+// in start during which t occurs, and the calendar year in which that
+// fiscal year ends.
+func fiscalMonth(t time.Time, start time.Month) (int, int) {
+	year, month, _ := t.Date()
+	if start <= time.January {
+		return int(month - time.January), year
+	}
+	if month >= start {
+		return int(month - start), year + 1
+	}
+	return int(month + 12 - start), year
+}
+
+func makeFiscalYearFormatter(o *options, _ int) func([]byte, time.Time) []byte {
+	// %{fiscal_year}  The fiscal year, numbered by the calendar year in
+	//                 which it ends.
+	start := o.fiscal
+	return func(buf []byte, t time.Time) []byte {
+		_, year := fiscalMonth(t, start)
+		return appendYear(buf, year)
+	}
+}
+
+func makeFiscalQuarterFormatter(o *options, _ int) func([]byte, time.Time) []byte {
+	// %{fiscal_quarter}  The quarter of the fiscal year (range 1 to 4).
+	start := o.fiscal
+	return func(buf []byte, t time.Time) []byte {
+		month, _ := fiscalMonth(t, start)
+		return append(buf, byte(month/3+'1'))
+	}
+}
+
+// The Julian date counts days from noon UTC on November 24, 4714 BC, in
+// the proleptic Gregorian calendar, and the modified Julian date counts
+// days from midnight UTC on November 17, 1858, which is Julian date
+// 2400000.5. Both ignore leap seconds, as Unix time does.
+const (
+	julianEpoch         = -210866760000 // Unix time of Julian date 0
+	modifiedJulianEpoch = -3506716800   // Unix time of modified Julian date 0
+
+	nanosecondsPerDay = secondsPerDay * 1000000000
+)
+
+// julianDays returns the number of whole days that have elapsed between
+// epoch, a Unix time, and t, rounded towards negative infinity, and the
+// number of nanoseconds that have elapsed since the last of those days
+// began.
+func julianDays(t time.Time, epoch int64) (int64, int64) {
+	sec := t.Unix() - epoch
+	days, rem := sec/secondsPerDay, sec%secondsPerDay
+	if rem < 0 {
+		days--
+		rem += secondsPerDay
+	}
+	return days, rem*1000000000 + int64(t.Nanosecond())
+}
+
+func makeJulianDayFormatter(epoch int64) func(*options, int) func([]byte, time.Time) []byte {
+	// %{jdn}   The Julian day number, which increments at noon UTC.
+	// %{mjdn}  The modified Julian day number, which increments at
+	//          midnight UTC.
+	return func(*options, int) func([]byte, time.Time) []byte {
+		return func(buf []byte, t time.Time) []byte {
+			days, _ := julianDays(t, epoch)
+			return strconv.AppendInt(buf, days, 10)
+		}
+	}
+}
+
+func makeJulianDateFormatter(epoch int64) func(*options, int) func([]byte, time.Time) []byte {
+	// %{jd}   The Julian date, with the number of fractional digits
+	//         selected by the field width, six by default.
+	// %{mjd}  The modified Julian date, like %{jd}.
+	return func(_ *options, precision int) func([]byte, time.Time) []byte {
+		return func(buf []byte, t time.Time) []byte {
+			days, nsec := julianDays(t, epoch)
+			if days < 0 {
+				// Format the magnitude of the negative date.
+				buf = append(buf, '-')
+				if days = -days; nsec > 0 {
+					days, nsec = days-1, nanosecondsPerDay-nsec
+				}
+			}
+			buf = strconv.AppendInt(buf, days, 10)
+			buf = append(buf, '.')
+			for i := 0; i < precision; i++ {
+				nsec *= 10
+				buf = append(buf, byte(nsec/nanosecondsPerDay)+'0')
+				nsec %= nanosecondsPerDay
+			}
+			return buf
+		}
+	}
+}
+
+func makeEpochFormatter(digits int) func(*options, int) func([]byte, time.Time) []byte {
+	// %{epoch}     The number of seconds since the Epoch, with the number
+	//              of fractional digits selected by the field width, nine
+	//              by default.
+	// %{epoch_ms}  The number of milliseconds since the Epoch.
+	// %{epoch_us}  The number of microseconds since the Epoch.
+	// %{epoch_ns}  The number of nanoseconds since the Epoch.
+	return func(_ *options, precision int) func([]byte, time.Time) []byte {
+		point := digits == 0
+		if !point {
+			precision = digits
+		}
+		divisor := 1
+		for i := precision; i < 9; i++ {
+			divisor *= 10
+		}
+		return func(buf []byte, t time.Time) []byte {
+			// Like %s, the result is rounded towards negative infinity,
+			// so that the fraction of a time before the Epoch is
+			// formatted as the magnitude of a negative number.
+			sec, fraction := t.Unix(), t.Nanosecond()/divisor
+			if sec < 0 {
+				buf = append(buf, '-')
+				if sec = -sec; fraction > 0 {
+					sec, fraction = sec-1, 1000000000/divisor-fraction
+				}
+			}
+			if point {
+				buf = strconv.AppendInt(buf, sec, 10)
+				buf = append(buf, '.')
+				return appendFraction(buf, fraction, precision)
+			}
+			if sec == 0 {
+				return strconv.AppendInt(buf, int64(fraction), 10)
+			}
+			buf = strconv.AppendInt(buf, sec, 10)
+			return appendFraction(buf, fraction, precision)
+		}
+	}
+}
+
+func makeEpochParser(digits int) func(*parseState) error {
+	what := map[int]string{0: "seconds", 3: "milliseconds", 6: "microseconds", 9: "nanoseconds"}[digits]
+	return func(ps *parseState) error {
+		start := ps.i
+		negative := ps.i < len(ps.value) && ps.value[ps.i] == '-'
+		if negative {
+			ps.i++
+		}
+		i := ps.i
+		for ps.i < len(ps.value) && ps.value[ps.i] >= '0' && ps.value[ps.i] <= '9' {
+			ps.i++
+		}
+		whole, fraction := ps.value[i:ps.i], ""
+		if whole == "" {
+			ps.i = start
+			return ps.errorf("expected number of %s since the Epoch", what)
+		}
+		if digits == 0 {
+			if ps.i < len(ps.value) && ps.value[ps.i] == '.' {
+				ps.i++
+				i = ps.i
+				for ps.i < len(ps.value) && ps.value[ps.i] >= '0' && ps.value[ps.i] <= '9' {
+					ps.i++
+				}
+				fraction = ps.value[i:ps.i]
+			}
+		} else if len(whole) > digits {
+			whole, fraction = whole[:len(whole)-digits], whole[len(whole)-digits:]
+		} else {
+			whole, fraction = "", whole
+			for len(fraction) < digits {
+				fraction = "0" + fraction
+			}
+		}
+		if len(whole) > 18 || len(fraction) == 0 || len(fraction) > 9 {
+			ps.i = start
+			return ps.errorf("expected number of %s since the Epoch", what)
+		}
+
+		var sec int64
+		for _, c := range whole {
+			sec = sec*10 + int64(c-'0')
+		}
+		var nsec int
+		for i := 0; i < 9; i++ {
+			nsec *= 10
+			if i < len(fraction) {
+				nsec += int(fraction[i] - '0')
+			}
+		}
+		if negative {
+			if sec = -sec; nsec > 0 {
+				sec, nsec = sec-1, 1000000000-nsec
+			}
+		}
+		ps.unix, ps.nanosecond = sec, nsec
+		ps.hasUnix = true
+		return nil
+	}
+}
+
+func makeExpandedYearFormatter(_ *options, precision int) func([]byte, time.Time) []byte {
+	// %{expanded_year}  The ISO 8601 expanded representation of the year,
+	//                   which is always signed, with the number of digits
+	//                   selected by the field width, four by default, or
+	//                   more when necessary.
+	return func(buf []byte, t time.Time) []byte {
+		year := t.Year()
+		if year < 0 {
+			buf = append(buf, '-')
+			year = -year
+		} else {
+			buf = append(buf, '+')
+		}
+		start := len(buf)
+		buf = strconv.AppendInt(buf, int64(year), 10)
+		return padField(buf, start, precision, '0', true)
+	}
+}
+
+func parseExpandedYear(ps *parseState) error {
+	start := ps.i
+	if ps.i == len(ps.value) || (ps.value[ps.i] != '+' && ps.value[ps.i] != '-') {
+		return ps.errorf("expected sign of expanded year")
+	}
+	negative := ps.value[ps.i] == '-'
+	ps.i++
+	year, err := ps.number(4, 9, 0, 999999999, false)
+	if err != nil {
+		ps.i = start
+		return err
+	}
+	if negative {
+		year = -year
+	}
+	ps.year = year
+	ps.hasYear = true
+	return nil
+}
+
+func makeEraNameFormatter(before, after string) func(*options, int) func([]byte, time.Time) []byte {
+	// %{era}         Either "BC" or "AD" according to the given year.
+	// %{common_era}  Either "BCE" or "CE" according to the given year.
+	return func(*options, int) func([]byte, time.Time) []byte {
+		return func(buf []byte, t time.Time) []byte {
+			if t.Year() < 1 {
+				return append(buf, before...)
+			}
+			return append(buf, after...)
+		}
+	}
+}
+
+func appendEraYear(buf []byte, t time.Time) []byte {
+	// %{era_year}  The year of the era, so that year 0 is 1 BC, which
+	//              is always positive.
+	year := t.Year()
+	if year < 1 {
+		year = 1 - year
+	}
+	return strconv.AppendInt(buf, int64(year), 10)
+}
@@ -0,0 +1,277 @@
+package gosft
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestExtendedVerbs(t *testing.T) {
+	tests := []struct {
+		format string
+		opts   []Option
+		when   time.Time
+		want   string
+	}{
+		{"%{half}", nil, time.Date(2026, time.June, 30, 0, 0, 0, 0, time.UTC), "1"},
+		{"%{half}", nil, time.Date(2026, time.July, 1, 0, 0, 0, 0, time.UTC), "2"},
+		{"Q%q FY%{fiscal_year}", nil, time.Date(2026, time.August, 1, 0, 0, 0, 0, time.UTC), "Q3 FY2026"},
+		{"Q%{fiscal_quarter} FY%{fiscal_year}", nil, time.Date(2026, time.August, 1, 0, 0, 0, 0, time.UTC), "Q3 FY2026"},
+		// The United States federal fiscal year begins in October.
+		{"Q%{fiscal_quarter} FY%{fiscal_year}", []Option{WithFiscalYearStart(time.October)}, time.Date(2025, time.September, 30, 0, 0, 0, 0, time.UTC), "Q4 FY2025"},
+		{"Q%{fiscal_quarter} FY%{fiscal_year}", []Option{WithFiscalYearStart(time.October)}, time.Date(2025, time.October, 1, 0, 0, 0, 0, time.UTC), "Q1 FY2026"},
+		{"Q%{fiscal_quarter} FY%{fiscal_year}", []Option{WithFiscalYearStart(time.October)}, time.Date(2026, time.August, 1, 0, 0, 0, 0, time.UTC), "Q4 FY2026"},
+		{"Q%{fiscal_quarter} FY%{fiscal_year}", []Option{WithFiscalYearStart(time.April)}, time.Date(2026, time.March, 31, 0, 0, 0, 0, time.UTC), "Q4 FY2026"},
+		{"Q%{fiscal_quarter} FY%{fiscal_year}", []Option{WithFiscalYearStart(time.April)}, time.Date(2026, time.July, 1, 0, 0, 0, 0, time.UTC), "Q2 FY2027"},
+		{"Q%{fiscal_quarter} FY%{fiscal_year}", []Option{WithFiscalYearStart(time.December)}, time.Date(2026, time.November, 30, 0, 0, 0, 0, time.UTC), "Q4 FY2026"},
+		{"Q%{fiscal_quarter} FY%{fiscal_year}", []Option{WithFiscalYearStart(time.December)}, time.Date(2026, time.December, 1, 0, 0, 0, 0, time.UTC), "Q1 FY2027"},
+		{"[%3{half}] [%_3{half}] [%-6{fiscal_year}]", nil, time.Date(2026, time.July, 1, 0, 0, 0, 0, time.UTC), "[002] [  2] [2026]"},
+		{"%Y-Q%q-H%{half}", nil, time.Date(2026, time.October, 17, 0, 0, 0, 0, time.UTC), "2026-Q4-H2"},
+	}
+
+	for _, c := range tests {
+		t.Run(c.format, func(t *testing.T) {
+			tf, err := NewWithOptions(c.format, append(c.opts, WithExtendedVerbs())...)
+			ensureError(t, err, nil)
+			if got, want := tf.Format(c.when), c.want; got != want {
+				t.Errorf("GOT: %q; WANT: %q", got, want)
+			}
+		})
+	}
+
+	t.Run("caching", func(t *testing.T) {
+		// The fractional Julian dates change within a second.
+		tf, err := NewWithOptions("%T %{jdn} %9{jd}", WithExtendedVerbs())
+		ensureError(t, err, nil)
+		cf := NewCachingFormatter(tf)
+
+		when := time.Date(2000, time.January, 1, 12, 0, 0, 0, time.UTC)
+		for _, nsec := range []int{0, 500000000, 999999999} {
+			when := when.Add(time.Duration(nsec))
+			if got, want := cf.Format(when), tf.Format(when); got != want {
+				t.Errorf("GOT: %q; WANT: %q", got, want)
+			}
+		}
+	})
+
+	t.Run("lenient", func(t *testing.T) {
+		tf, err := NewWithOptions("%{half} %{bogus} %{", WithExtendedVerbs(), WithLenientVerbs())
+		ensureError(t, err, nil)
+		if got, want := tf.Format(time.Date(2026, time.July, 1, 0, 0, 0, 0, time.UTC)), "2 %{bogus} %{"; got != want {
+			t.Errorf("GOT: %q; WANT: %q", got, want)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := NewWithOptions("%F %{bogus}", WithExtendedVerbs())
+		ensureError(t, err, errors.New(`cannot recognize extended format verb "bogus" at index 4`))
+
+		_, err = NewWithOptions("%F %{half", WithExtendedVerbs())
+		ensureError(t, err, errors.New("cannot find closing format verb at index 3"))
+
+		_, err = NewParser("%F %{half}")
+		ensureError(t, err, errors.New(`cannot recognize extended format verb "half" at index 4`))
+	})
+}
+
+func TestJulianDates(t *testing.T) {
+	tf, err := NewWithOptions("%{jdn} %{jd} %{mjdn} %{mjd}", WithExtendedVerbs())
+	ensureError(t, err, nil)
+
+	tests := []struct {
+		when time.Time
+		want string
+	}{
+		// J2000.0
+		{time.Date(2000, time.January, 1, 12, 0, 0, 0, time.UTC), "2451545 2451545.000000 51544 51544.500000"},
+		{time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC), "2451544 2451544.500000 51544 51544.000000"},
+		{time.Date(2000, time.January, 1, 18, 0, 0, 0, time.FixedZone("XST", 6*60*60)), "2451545 2451545.000000 51544 51544.500000"},
+		// The Unix time epoch.
+		{time.Unix(0, 0).UTC(), "2440587 2440587.500000 40587 40587.000000"},
+		{time.Date(1969, time.December, 31, 23, 59, 59, 913600000, time.UTC), "2440587 2440587.499999 40586 40586.999999"},
+		// The epochs of the modified and unmodified Julian dates.
+		{time.Date(1858, time.November, 17, 0, 0, 0, 0, time.UTC), "2400000 2400000.500000 0 0.000000"},
+		{time.Date(1858, time.November, 16, 18, 0, 0, 0, time.UTC), "2400000 2400000.250000 -1 -0.250000"},
+		{time.Date(-4713, time.November, 24, 12, 0, 0, 0, time.UTC), "0 0.000000 -2400001 -2400000.500000"},
+		{time.Date(-4713, time.November, 24, 6, 0, 0, 0, time.UTC), "-1 -0.250000 -2400001 -2400000.750000"},
+		// Sputnik 1 was launched at 19:28:34 UTC on October 4, 1957.
+		{time.Date(1957, time.October, 4, 19, 28, 34, 0, time.UTC), "2436116 2436116.311504 36115 36115.811504"},
+	}
+
+	for _, c := range tests {
+		if got, want := tf.Format(c.when), c.want; got != want {
+			t.Errorf("%v: GOT: %q; WANT: %q", c.when, got, want)
+		}
+	}
+
+	t.Run("precision", func(t *testing.T) {
+		when := time.Date(2000, time.January, 1, 12, 0, 0, 86400, time.UTC)
+
+		tests := []struct {
+			format, want string
+		}{
+			{"%1{jd}", "2451545.0"},
+			{"%9{jd}", "2451545.000000001"},
+			{"%12{mjd}", "51544.500000001"},
+			{"%_12{jdn}", "     2451545"},
+		}
+
+		for _, c := range tests {
+			tf, err := NewWithOptions(c.format, WithExtendedVerbs())
+			ensureError(t, err, nil)
+			if got, want := tf.Format(when), c.want; got != want {
+				t.Errorf("%s: GOT: %q; WANT: %q", c.format, got, want)
+			}
+		}
+	})
+}
+
+func TestEpochVerbs(t *testing.T) {
+	const format = "%{epoch_ms} %{epoch_us} %{epoch_ns} %3{epoch}"
+	tf, err := NewWithOptions(format, WithExtendedVerbs())
+	ensureError(t, err, nil)
+
+	tests := []struct {
+		when time.Time
+		want string
+	}{
+		{time.Unix(1700000000, 123456789), "1700000000123 1700000000123456 1700000000123456789 1700000000.123"},
+		{time.Unix(1700000000, 0), "1700000000000 1700000000000000 1700000000000000000 1700000000.000"},
+		{time.Unix(0, 0), "0 0 0 0.000"},
+		{time.Unix(0, 1), "0 0 1 0.000"},
+		{time.Unix(0, 1500000), "1 1500 1500000 0.001"},
+		// Times before the Epoch are rounded towards negative infinity,
+		// like %s.
+		{time.Unix(0, -1), "-1 -1 -1 -0.001"},
+		{time.Unix(0, -1500000), "-2 -1500 -1500000 -0.002"},
+		{time.Unix(-1, 0), "-1000 -1000000 -1000000000 -1.000"},
+		{time.Unix(-1700000000, -123456789), "-1700000000124 -1700000000123457 -1700000000123456789 -1700000000.124"},
+		// Apollo 11 landed on the moon at 20:17:40 UTC on July 20, 1969.
+		{time.Date(1969, time.July, 20, 20, 17, 40, 0, time.UTC), "-14182940000 -14182940000000 -14182940000000000 -14182940.000"},
+	}
+
+	for _, c := range tests {
+		if got, want := tf.Format(c.when), c.want; got != want {
+			t.Errorf("%v: GOT: %q; WANT: %q", c.when, got, want)
+		}
+	}
+
+	t.Run("precision", func(t *testing.T) {
+		when := time.Unix(-1700000000, -123456789)
+
+		tests := []struct {
+			format, want string
+		}{
+			{"%{epoch}", "-1700000000.123456789"},
+			{"%1{epoch}", "-1700000000.2"},
+			{"%6{epoch}", "-1700000000.123457"},
+			{"%_20{epoch_ms}", "      -1700000000124"},
+			{"%020{epoch_ms}", "-0000001700000000124"},
+		}
+
+		for _, c := range tests {
+			tf, err := NewWithOptions(c.format, WithExtendedVerbs())
+			ensureError(t, err, nil)
+			if got, want := tf.Format(when), c.want; got != want {
+				t.Errorf("%s: GOT: %q; WANT: %q", c.format, got, want)
+			}
+		}
+	})
+
+	t.Run("round trip", func(t *testing.T) {
+		for _, format := range []string{"%{epoch_ms}", "%{epoch_us}", "%{epoch_ns}", "%{epoch}", "%3{epoch}", "%_24{epoch_ns}"} {
+			tf, err := NewWithOptions(format, WithExtendedVerbs())
+			ensureError(t, err, nil)
+			p, err := NewParser(format)
+			ensureError(t, err, nil)
+
+			precision := map[string]time.Duration{
+				"%{epoch_ms}": time.Millisecond,
+				"%{epoch_us}": time.Microsecond,
+				"%3{epoch}":   time.Millisecond,
+			}[format]
+			if precision == 0 {
+				precision = time.Nanosecond
+			}
+
+			for _, c := range tests {
+				// Rounding towards negative infinity is truncation
+				// relative to the zero time.
+				want := c.when.Add(-time.Duration(c.when.Nanosecond()) % precision).UTC()
+				got, err := p.Parse(tf.Format(c.when))
+				ensureError(t, err, nil)
+				if !got.Equal(want) {
+					t.Errorf("%s: %v: GOT: %v; WANT: %v", format, c.when, got, want)
+				}
+			}
+		}
+	})
+
+	t.Run("parse errors", func(t *testing.T) {
+		tests := []struct {
+			format, value, want string
+		}{
+			{"%{epoch_ms}", "x", "expected number of milliseconds since the Epoch"},
+			{"%{epoch}", "1700000000", "expected number of seconds since the Epoch"},
+			{"%{epoch}", "1700000000.1234567891", "cannot parse"},
+			{"%{epoch_ns}", "-", "expected number of nanoseconds since the Epoch"},
+		}
+
+		for _, c := range tests {
+			p, err := NewParser(c.format)
+			ensureError(t, err, nil)
+			_, err = p.Parse(c.value)
+			ensureError(t, err, errors.New(c.want))
+		}
+	})
+}
+
+func TestYearVerbs(t *testing.T) {
+	tf, err := NewWithOptions("%{expanded_year} %6{expanded_year} %{era_year} %{era} %{common_era}", WithExtendedVerbs())
+	ensureError(t, err, nil)
+
+	tests := []struct {
+		year int
+		want string
+	}{
+		{12345, "+12345 +012345 12345 AD CE"},
+		{2026, "+2026 +002026 2026 AD CE"},
+		{1, "+0001 +000001 1 AD CE"},
+		{0, "+0000 +000000 1 BC BCE"},
+		// Julius Caesar was assassinated on March 15, 44 BC.
+		{-43, "-0043 -000043 44 BC BCE"},
+		{-12345, "-12345 -012345 12346 BC BCE"},
+	}
+
+	for _, c := range tests {
+		when := time.Date(c.year, time.March, 15, 0, 0, 0, 0, time.UTC)
+		if got, want := tf.Format(when), c.want; got != want {
+			t.Errorf("%d: GOT: %q; WANT: %q", c.year, got, want)
+		}
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		for _, format := range []string{"%{expanded_year}-%m-%d", "%6{expanded_year}-%m-%d", "%Y-%m-%d"} {
+			tf, err := NewWithOptions(format, WithExtendedVerbs())
+			ensureError(t, err, nil)
+			p, err := NewParser(format)
+			ensureError(t, err, nil)
+
+			for _, c := range tests {
+				want := time.Date(c.year, time.March, 15, 0, 0, 0, 0, time.UTC)
+				got, err := p.Parse(tf.Format(want))
+				ensureError(t, err, nil)
+				if !got.Equal(want) {
+					t.Errorf("%s: GOT: %v; WANT: %v", format, got, want)
+				}
+			}
+		}
+
+		p, err := NewParser("%{expanded_year}")
+		ensureError(t, err, nil)
+		_, err = p.Parse("2026")
+		ensureError(t, err, errors.New("expected sign of expanded year"))
+		_, err = p.Parse("+1234567890")
+		ensureError(t, err, errors.New("at index 10: extra text"))
+	})
+}
@@ -1,9 +1,11 @@
 package gosft
 
 import (
-	"errors"
 	"fmt"
+	"io"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 	"unicode/utf8"
 )
@@ -12,10 +14,15 @@ import (
 // configured format specification. A single Formatter may safely be
 // used by multiple Go routines simultaneously.
 type Formatter struct {
-	formatters []func(*[]byte, time.Time)
-	size       int
+	program []instruction
+	needs   needs
+	size    int
+	wide    int            // size for years outside 0 through 9999
+	loc     *time.Location // when not nil, times are converted to loc
 }
 
+// formatMap maps each of the layouts predefined by the time package to
+// an equivalent format string.
 var formatMap map[string]string
 
 func init() {
@@ -28,12 +35,12 @@ func init() {
 		time.RFC850:      "%A, %d-%b-%y %T %Z", // "Monday, 02-Jan-06 15:04:05 MST",
 		time.RFC1123:     "%a, %d %b %Y %T %Z", // "Mon, 02 Jan 2006 15:04:05 MST",
 		time.RFC1123Z:    "%a, %d %b %Y %T %z", // "Mon, 02 Jan 2006 15:04:05 -0700",
-		time.RFC3339:     "%Y-%m-%dT%T%1",      // "2006-01-02T15:04:05Z07:00", // TODO: %1 not standard
-		time.RFC3339Nano: "%Y-%m-%dT%T.%N%1",   // "2006-01-02T15:04:05.999999999Z07:00", // TODO: %1 not standard
-		time.Kitchen:     "%2:%M%p",            // "3:04PM", // TODO: %2 not standard
+		time.RFC3339:     "%Y-%m-%dT%T%K",      // "2006-01-02T15:04:05Z07:00",
+		time.RFC3339Nano: "%Y-%m-%dT%T.%N%K",   // "2006-01-02T15:04:05.999999999Z07:00",
+		time.Kitchen:     "%-I:%M%p",           // "3:04PM",
 		time.Stamp:       "%b %e %T",           // "Jan _2 15:04:05"
-		time.StampMilli:  "%b %e %T.%3",        // "Jan _2 15:04:05.000" // TODO: %3 not standard
-		time.StampMicro:  "%b %e %T.%4",        // "Jan _2 15:04:05.000000" // TODO: %4 not standard
+		time.StampMilli:  "%b %e %T.%3N",       // "Jan _2 15:04:05.000"
+		time.StampMicro:  "%b %e %T.%6N",       // "Jan _2 15:04:05.000000"
 		time.StampNano:   "%b %e %T.%N",        // "Jan _2 15:04:05.000000000"
 	}
 }
@@ -41,155 +48,297 @@ func init() {
 // New returns a formatter that formats times according to the
 // provided format string.
 func New(format string) (*Formatter, error) {
-	return create(format, false)
+	return create(format, &options{})
 }
 
 // NewCompat returns a formatter that formats times according to the
-// provided Go standard library compatible time string format.
+// provided Go standard library compatible time string format. Any
+// layout accepted by time.Time.Format may be used, and the formatter
+// produces the same output as that method.
 func NewCompat(format string) (*Formatter, error) {
-	value, ok := formatMap[format]
-	if !ok {
-		return nil, fmt.Errorf("cannot find equivalent for time format string: %q", format)
-	}
-	return create(value, true)
+	return newFormatter(compileLayout(format)), nil
 }
 
-func create(format string, special bool) (*Formatter, error) {
-	// Build slice of formatting functions, each will emit the
-	// requested information.
-	var formatters []func(*[]byte, time.Time)
+func create(format string, o *options) (*Formatter, error) {
+	program, err := compile(nil, format, o)
+	if err != nil {
+		return nil, err
+	}
+	return newFormatter(program), nil
+}
 
+// compile appends to program the instructions that emit each verb and
+// the text between them. Verbs having neither flags, a field width, nor
+// a locale or modifier dependent representation are compiled into
+// opcodes operating on the broken-down time, and composite verbs into
+// the instructions of their expansions.
+func compile(program []instruction, format string, o *options) ([]instruction, error) {
 	var buf []byte
 	var foundPercent bool
+	var percent int // index of the '%' beginning the current verb
+	var modifier rune
+	var flags []byte
+	var width int
+	var colons int
+	var skip int // index following an extended verb
 
 	for ri, rune := range format {
+		if ri < skip {
+			continue
+		}
 		if !foundPercent {
 			if rune == '%' {
 				foundPercent = true
+				percent = ri
 				if len(buf) > 0 {
-					formatters = append(formatters, makeStringFormatter(buf))
+					program = appendText(program, string(buf))
 					buf = nil
 				}
 			} else {
-				appendRune(&buf, rune)
+				buf = appendRune(buf, rune)
 			}
 			continue
 		}
-		switch rune {
-		case 'a':
-			formatters = append(formatters, appendWeekdayShort)
-		case 'A':
-			formatters = append(formatters, appendWeekdayLong)
-		case 'b':
-			formatters = append(formatters, appendMonthShort)
-		case 'B':
-			formatters = append(formatters, appendMonthLong)
-		case 'c':
-			formatters = append(formatters, appendC)
-		case 'C':
-			formatters = append(formatters, appendCC)
-		case 'd':
-			formatters = append(formatters, appendD)
-		case 'D':
-			formatters = append(formatters, appendDC)
-		case 'e':
-			formatters = append(formatters, appendE)
-		case 'F':
-			formatters = append(formatters, appendFC)
-		case 'g':
-			formatters = append(formatters, appendG)
-		case 'G':
-			formatters = append(formatters, appendGC)
-		case 'h':
-			formatters = append(formatters, appendMonthShort)
-		case 'H':
-			formatters = append(formatters, appendHC)
-		case 'I':
-			formatters = append(formatters, appendIC)
-		case 'j':
-			formatters = append(formatters, appendJ)
-		case 'k':
-			formatters = append(formatters, appendK)
-		case 'l':
-			formatters = append(formatters, appendL)
-		case 'm':
-			formatters = append(formatters, appendM)
-		case 'M':
-			formatters = append(formatters, appendMC)
-		case 'n':
-			formatters = append(formatters, appendN)
-		case 'N':
-			formatters = append(formatters, appendNC)
-		case 'p':
-			formatters = append(formatters, appendP)
-		case 'P':
-			formatters = append(formatters, appendPC)
-		case 'r':
-			formatters = append(formatters, appendR)
-		case 'R':
-			formatters = append(formatters, appendRC)
-		case 's':
-			formatters = append(formatters, appendS)
-		case 'S':
-			formatters = append(formatters, appendSC)
-		case 't':
-			formatters = append(formatters, appendT)
-		case 'T':
-			formatters = append(formatters, appendTC)
-		case 'u':
-			formatters = append(formatters, appendU)
-		case 'w':
-			formatters = append(formatters, appendW)
-		case 'x':
-			formatters = append(formatters, appendX)
-		case 'X':
-			formatters = append(formatters, appendXC)
-		case 'y':
-			formatters = append(formatters, appendY)
-		case 'Y':
-			formatters = append(formatters, appendYC)
-		case 'z':
-			formatters = append(formatters, appendZ)
-		case 'Z':
-			formatters = append(formatters, appendZC)
-		case '%':
-			formatters = append(formatters, appendPercent)
-		case '+':
-			formatters = append(formatters, appendPlus)
-		case '1':
-			if !special {
-				return nil, fmt.Errorf("cannot recognize format verb %q at index %d", rune, ri)
+		if modifier == 0 && colons == 0 {
+			// Flags precede the optional field width, which precedes
+			// the optional modifier or colons.
+			if width == 0 && strings.ContainsRune(formatFlags, rune) {
+				flags = append(flags, byte(rune))
+				continue
 			}
-			formatters = append(formatters, appendTZ)
-		case '2':
-			if !special {
-				return nil, fmt.Errorf("cannot recognize format verb %q at index %d", rune, ri)
+			if rune >= '0' && rune <= '9' {
+				if width = width*10 + int(rune-'0'); width > maxWidth {
+					return nil, fmt.Errorf("cannot use field width greater than %d at index %d", maxWidth, ri)
+				}
+				continue
 			}
-			formatters = append(formatters, appendLMin)
-		case '3':
-			if !special {
-				return nil, fmt.Errorf("cannot recognize format verb %q at index %d", rune, ri)
+		}
+		if modifier == 0 && (rune == 'E' || rune == 'O') {
+			modifier = rune
+			continue
+		}
+		if modifier == 0 && rune == ':' && colons < 3 {
+			colons++
+			continue
+		}
+		if colons > 0 && rune != 'z' {
+			return nil, fmt.Errorf("cannot use colons with format verb %q at index %d", rune, ri)
+		}
+
+		var f func([]byte, time.Time) []byte
+		var err error
+		var subsecond bool // whether the result may change within a second
+
+		_, size := utf8.DecodeRuneInString(format[ri:])
+		next := ri + size // index following the verb
+		defaultWidth := numericVerbs[rune]
+
+		if rune == '{' && modifier == 0 && colons == 0 {
+			// An extended verb is named between braces, such as
+			// %{fiscal_year}.
+			end := strings.IndexByte(format[ri:], '}')
+			if end < 0 {
+				break // report the incomplete verb as a trailing '%'
 			}
-			formatters = append(formatters, appendMilli)
-		case '4':
-			if !special {
-				return nil, fmt.Errorf("cannot recognize format verb %q at index %d", rune, ri)
+			name := format[ri+1 : ri+end]
+			next = ri + end + 1
+			skip = next
+			if ev, ok := extendedVerbs[name]; ok && o.extended {
+				precision := ev.precision
+				if precision > 0 && width > 0 {
+					// The field width of a fractional verb is its
+					// precision.
+					precision, width = width, 0
+					if precision > 9 {
+						precision = 9
+					}
+				}
+				f, defaultWidth, subsecond = ev.make(o, precision), ev.width, ev.subsecond
+			} else if !o.lenient {
+				return nil, &UnknownVerbError{Format: format, Offset: ri, Verb: rune, Name: name}
+			}
+		}
+		if modifier != 0 {
+			if !strings.ContainsRune(modifiedVerbs[modifier], rune) {
+				return nil, &UnsupportedModifierError{Format: format, Offset: ri, Modifier: modifier, Verb: rune}
 			}
-			formatters = append(formatters, appendMicro)
-		default:
-			return nil, fmt.Errorf("cannot recognize format verb %q at index %d", rune, ri)
+			// Without an alternative representation, f remains nil and
+			// the unmodified verb is used.
+			if f, subsecond, err = makeModifiedFormatter(modifier, rune, o.locale); err != nil {
+				return nil, fmt.Errorf("%w at index %d", err, ri)
+			}
+		}
+		if f == nil && o.locale != nil {
+			if f, subsecond, err = makeLocaleFormatter(rune, o.locale); err != nil {
+				return nil, fmt.Errorf("%w at index %d", err, ri)
+			}
+		}
+		if f == nil && colons > 0 {
+			f = colonOffsetFormatters[colons]
+		}
+		plain := f == nil && len(flags) == 0 && width == 0
+		if f == nil {
+			f = verbFormatter(rune)
 		}
+		if f == nil {
+			// The result of a custom verb may change within a second.
+			f = o.customVerb(rune)
+			subsecond = f != nil
+		}
+		if f == nil && !o.lenient {
+			return nil, &UnknownVerbError{Format: format, Offset: ri, Verb: rune}
+		}
+
+		if f != nil && (len(flags) > 0 || width > 0) {
+			if colons > 0 && width == 0 {
+				width = colonOffsetWidths[colons]
+			}
+			if rune == 'N' {
+				// The field width of %N is its precision.
+				f = makeFractionFormatter(flags, width)
+			} else {
+				f = makePaddedFormatter(rune, f, flags, width, defaultWidth)
+			}
+		}
+
+		if f == nil {
+			// A lenient formatter emits an unrecognized verb verbatim.
+			program = appendText(program, format[percent:next])
+		} else if op, ok := verbOpcodes[rune]; plain && ok {
+			program = append(program, instruction{op: op})
+		} else if text, ok := verbTexts[rune]; plain && ok {
+			program = appendText(program, text)
+		} else if expansion, ok := compositeExpansions[rune]; plain && ok {
+			if program, err = compile(program, expansion, o); err != nil {
+				return nil, err
+			}
+		} else if subsecond || rune == 'N' {
+			program = append(program, instruction{op: opSubsecond, call: f})
+		} else {
+			program = append(program, instruction{op: opCall, call: f})
+		}
+
 		foundPercent = false
+		modifier = 0
+		flags = nil
+		width = 0
+		colons = 0
 	}
 
 	if foundPercent {
-		return nil, errors.New("cannot find closing format verb")
+		if !o.lenient {
+			return nil, &TrailingPercentError{Format: format, Offset: percent}
+		}
+		program = appendText(program, format[percent:])
 	}
 
 	if len(buf) > 0 {
-		formatters = append(formatters, makeStringFormatter(buf))
+		program = appendText(program, string(buf))
+	}
+
+	return program, nil
+}
+
+// verbFormatter returns the formatter for a built-in format verb having
+// neither flags, a field width, nor a modifier, or nil when verb is not
+// a built-in verb.
+func verbFormatter(verb rune) func([]byte, time.Time) []byte {
+	switch verb {
+	case 'a':
+		return appendWeekdayShort
+	case 'A':
+		return appendWeekdayLong
+	case 'b':
+		return appendMonthShort
+	case 'B':
+		return appendMonthLong
+	case 'c':
+		return appendC
+	case 'C':
+		return appendCC
+	case 'd':
+		return appendD
+	case 'D':
+		return appendDC
+	case 'e':
+		return appendE
+	case 'F':
+		return appendFC
+	case 'g':
+		return appendG
+	case 'G':
+		return appendGC
+	case 'h':
+		return appendMonthShort
+	case 'H':
+		return appendHC
+	case 'I':
+		return appendIC
+	case 'j':
+		return appendJ
+	case 'k':
+		return appendK
+	case 'K':
+		return appendKC
+	case 'l':
+		return appendL
+	case 'm':
+		return appendM
+	case 'M':
+		return appendMC
+	case 'n':
+		return appendN
+	case 'N':
+		return appendNC
+	case 'p':
+		return appendP
+	case 'P':
+		return appendPC
+	case 'q':
+		return appendQ
+	case 'r':
+		return appendR
+	case 'R':
+		return appendRC
+	case 's':
+		return appendS
+	case 'S':
+		return appendSC
+	case 't':
+		return appendT
+	case 'T':
+		return appendTC
+	case 'u':
+		return appendU
+	case 'U':
+		return appendUC
+	case 'V':
+		return appendVC
+	case 'w':
+		return appendW
+	case 'W':
+		return appendWC
+	case 'x':
+		return appendX
+	case 'X':
+		return appendXC
+	case 'y':
+		return appendY
+	case 'Y':
+		return appendYC
+	case 'z':
+		return appendZ
+	case 'Z':
+		return appendZC
+	case '%':
+		return appendPercent
+	case '+':
+		return appendPlus
 	}
+	return nil
+}
 
+func newFormatter(program []instruction) *Formatter {
 	// When instantiating a formatter, want to calculate and store the
 	// longest slice of bytes that are needed to format any time using
 	// the specified time format string. For this reason, create a
@@ -202,42 +351,360 @@ func create(format string, special bool) (*Formatter, error) {
 	// that byte slice.
 	when := time.Date(2021, time.September, 30, 23, 59, 59, 123456789, time.UTC)
 
-	tf := &Formatter{formatters: formatters}
-	tf.size = len(tf.Format(when))
+	tf := &Formatter{program: program, needs: programNeeds(program)}
+	tf.size = len(tf.Append(nil, when))
 
-	return tf, nil
+	// Years outside 0 through 9999 have a sign or more digits. This
+	// year is a multiple of 400 years before 2021, so that its dates
+	// fall on the same weekdays.
+	when = when.AddDate(-1000000000, 0, 0)
+	tf.wide = len(tf.Append(nil, when))
+
+	return tf
 }
 
-// Append will format t in accordance with its preconfigured format
-// specification and append the formatted bytes to buf.
-func (tf *Formatter) Append(buf []byte, t time.Time) []byte {
-	for _, f := range tf.formatters {
-		f(&buf, t)
+// Years from 0 through 9999 are formatted using four digits. The
+// bounds are a day within that range, so that they hold in every
+// location.
+var (
+	narrowStart = time.Date(0, time.January, 2, 0, 0, 0, 0, time.UTC)
+	narrowEnd   = time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+)
+
+// sizeFor returns the size of the buffer needed to format t.
+func (tf *Formatter) sizeFor(t time.Time) int {
+	if t.Before(narrowStart) || !t.Before(narrowEnd) {
+		return tf.wide
 	}
-	return buf
+	return tf.size
+}
+
+// subsecond reports whether the result of tf may change within a
+// second.
+func (tf *Formatter) subsecond() bool {
+	for _, in := range tf.program {
+		if in.op == opNanosecond || in.op == opSubsecond {
+			return true
+		}
+	}
+	return false
+}
+
+// In returns a formatter that formats times in the same manner as tf,
+// after converting them to loc, so that %Z and %z reflect loc rather
+// than the location of each time. In panics if loc is nil.
+func (tf *Formatter) In(loc *time.Location) *Formatter {
+	if loc == nil {
+		panic("gosft: Formatter.In called with nil location")
+	}
+	c := *tf
+	c.loc = loc
+	return &c
 }
 
 // Format will format t and return a string in accordance with its
 // preconfigured format specification.
 func (tf *Formatter) Format(t time.Time) string {
-	return string(tf.Append(make([]byte, 0, tf.size), t))
+	return string(tf.Append(make([]byte, 0, tf.sizeFor(t)), t))
 }
 
-func makeStringFormatter(value []byte) func(*[]byte, time.Time) {
-	return func(buf *[]byte, _ time.Time) {
-		*buf = append(*buf, value...)
+// Write will format t in accordance with its preconfigured format
+// specification and write the formatted bytes to w, returning the
+// number of bytes written and any error encountered. It formats into a
+// pooled buffer, or directly into the unused buffer space of writers
+// such as bufio.Writer, and does not allocate memory.
+func (tf *Formatter) Write(w io.Writer, t time.Time) (int, error) {
+	b := bufferPool.Get().(*buffer)
+	pooled := (*b)[:0]
+	ab, direct := w.(availableBufferer)
+	if direct {
+		*b = ab.AvailableBuffer()
+	} else {
+		*b = pooled
+	}
+	*b = tf.Append(*b, t)
+	n, err := w.Write(*b)
+	if direct {
+		*b = pooled // do not retain the buffer of the writer
 	}
+	putBuffer(b)
+	return n, err
 }
 
-func appendRune(buf *[]byte, r rune) {
-	if r < utf8.RuneSelf {
-		*buf = append(*buf, byte(r))
+// Fprintf will write t formatted in accordance with its preconfigured
+// format specification, immediately followed by the message formatted
+// by fmt.Fprintf using format and a, to w in a single call to its Write
+// method. It returns the number of bytes written and any error
+// encountered.
+func (tf *Formatter) Fprintf(w io.Writer, t time.Time, format string, a ...interface{}) (int, error) {
+	b := bufferPool.Get().(*buffer)
+	*b = (*b)[:0]
+	*b = tf.Append(*b, t)
+	fmt.Fprintf(b, format, a...) // writes to a buffer never fail
+	n, err := w.Write(*b)
+	putBuffer(b)
+	return n, err
+}
+
+// availableBufferer is implemented by writers such as bufio.Writer that
+// provide their unused buffer space as an empty slice, which may be
+// appended to and passed to their Write method without copying.
+type availableBufferer interface {
+	AvailableBuffer() []byte
+}
+
+// buffer is an io.Writer that appends to itself.
+type buffer []byte
+
+func (b *buffer) Write(p []byte) (int, error) {
+	*b = append(*b, p...)
+	return len(p), nil
+}
+
+// maxPooledBuffer is the capacity above which a buffer is not returned
+// to bufferPool, preventing one long message from being retained.
+const maxPooledBuffer = 64 << 10
+
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		b := make(buffer, 0, 128)
+		return &b
+	},
+}
+
+func putBuffer(b *buffer) {
+	if cap(*b) > maxPooledBuffer {
 		return
 	}
-	olen := len(*buf)
-	*buf = append(*buf, 0, 0, 0, 0)              // grow buf large enough to accommodate largest possible UTF8 sequence
-	n := utf8.EncodeRune((*buf)[olen:olen+4], r) // encode rune into newly allocated buf space
-	*buf = (*buf)[:olen+n]                       // trim buf to actual size used by rune addition
+	bufferPool.Put(b)
+}
+
+// formatFlags lists the GNU flag characters that may follow the '%'
+// character of a format verb:
+//
+//	'-'  do not pad a numeric result
+//	'_'  pad a numeric result with spaces
+//	'0'  pad a numeric result with zeros, even for verbs that
+//	     normally pad with spaces
+//	'^'  convert alphabetic characters in the result to uppercase
+//	'#'  swap the case of the result: uppercase for %a, %A, %b, %B
+//	     and %h, and lowercase for %p and %Z
+const formatFlags = "-_0^#"
+
+// maxWidth is the largest field width accepted by a format verb, which
+// prevents a typo in a format string from requesting an enormous field.
+const maxWidth = 1024
+
+// numericVerbs maps each format verb that emits a number to its default
+// field width. The result of a numeric verb has its padding replaced
+// when a flag or field width is provided.
+var numericVerbs = map[rune]int{
+	'C': 2, 'd': 2, 'e': 2, 'g': 2, 'G': 4, 'H': 2, 'I': 2, 'j': 3, 'k': 2, 'l': 2,
+	'm': 2, 'M': 2, 'q': 1, 's': 1, 'S': 2, 'u': 1, 'U': 2, 'V': 2, 'w': 1, 'W': 2,
+	'y': 2, 'Y': 4, 'z': 5,
+}
+
+// spacePaddedVerbs lists the numeric format verbs that pad with spaces
+// rather than zeros by default.
+const spacePaddedVerbs = "ekl"
+
+// makePaddedFormatter returns a formatter that applies the provided
+// flags and field width to the result of f, which formats verb. The
+// defaultWidth of a numeric verb is greater than zero.
+func makePaddedFormatter(verb rune, f func([]byte, time.Time) []byte, flags []byte, width, defaultWidth int) func([]byte, time.Time) []byte {
+	var pad byte
+	var upper, lower bool
+
+	for _, flag := range flags {
+		switch flag {
+		case '-', '_', '0':
+			pad = flag
+		case '^':
+			upper, lower = true, false
+		case '#':
+			switch verb {
+			case 'a', 'A', 'b', 'B', 'h':
+				upper, lower = true, false
+			case 'p', 'Z':
+				upper, lower = false, true
+			}
+		}
+	}
+
+	numeric := defaultWidth > 0
+	if numeric && width == 0 {
+		width = defaultWidth
+	}
+
+	switch pad {
+	case '-':
+		pad = 0
+	case '_':
+		pad = ' '
+	case 0:
+		if numeric && !strings.ContainsRune(spacePaddedVerbs, verb) {
+			pad = '0'
+		} else {
+			pad = ' '
+		}
+	}
+
+	return func(buf []byte, t time.Time) []byte {
+		start := len(buf)
+		buf = f(buf, t)
+		if numeric {
+			buf = trimNumber(buf, start)
+		}
+		if upper || lower {
+			buf = changeCase(buf, start, upper)
+		}
+		if pad != 0 {
+			buf = padField(buf, start, width, pad, numeric)
+		}
+		return buf
+	}
+}
+
+// trimNumber removes the padding from the number formatted at
+// buf[start:], leaving its sign and at least one digit.
+func trimNumber(b []byte, start int) []byte {
+	i := start
+	for i < len(b) && b[i] == ' ' {
+		i++
+	}
+	var sign byte
+	if i < len(b) && (b[i] == '+' || b[i] == '-') {
+		sign = b[i]
+		i++
+	}
+	for i < len(b)-1 && (b[i] == '0' || b[i] == ' ') {
+		i++
+	}
+	j := start
+	if sign != 0 {
+		b[j] = sign
+		j++
+	}
+	j += copy(b[j:], b[i:])
+	return b[:j]
+}
+
+// changeCase converts the result at buf[start:] to uppercase or
+// lowercase.
+func changeCase(buf []byte, start int, upper bool) []byte {
+	b := buf[start:]
+	for _, c := range b {
+		if c >= utf8.RuneSelf {
+			// Locale dependent names may have non-ASCII characters.
+			var s string
+			if upper {
+				s = strings.ToUpper(string(b))
+			} else {
+				s = strings.ToLower(string(b))
+			}
+			return append(buf[:start], s...)
+		}
+	}
+	for i, c := range b {
+		if upper && c >= 'a' && c <= 'z' {
+			b[i] = c - ('a' - 'A')
+		} else if !upper && c >= 'A' && c <= 'Z' {
+			b[i] = c + ('a' - 'A')
+		}
+	}
+	return buf
+}
+
+// padField pads the result at buf[start:] with pad until it is width
+// characters wide. Zeros are inserted after the sign of a number.
+func padField(buf []byte, start, width int, pad byte, numeric bool) []byte {
+	n := width - utf8.RuneCount(buf[start:])
+	if n <= 0 {
+		return buf
+	}
+	olen := len(buf)
+	for i := 0; i < n; i++ {
+		buf = append(buf, pad)
+	}
+	if numeric && pad == '0' && (buf[start] == '+' || buf[start] == '-') {
+		start++
+	}
+	copy(buf[start+n:], buf[start:olen])
+	for i := start; i < start+n; i++ {
+		buf[i] = pad
+	}
+	return buf
+}
+
+// makeFractionFormatter returns a formatter for %N having the provided
+// flags and field width. As with GNU date(1), the field width of %N is
+// the number of digits of precision, from 1 to 9, with larger widths
+// padded on the right with zeros. The '-' flag trims trailing zeros,
+// like the .999 layout element of the time package, although at least
+// one digit remains. The '_' flag also trims trailing zeros, but pads
+// the result on the right with spaces.
+func makeFractionFormatter(flags []byte, width int) func([]byte, time.Time) []byte {
+	var pad byte
+	for _, flag := range flags {
+		switch flag {
+		case '-', '_', '0':
+			pad = flag
+		}
+	}
+	if width == 0 {
+		width = 9
+	}
+	precision := width
+	if precision > 9 {
+		precision = 9
+	}
+	divisor := 1
+	for i := precision; i < 9; i++ {
+		divisor *= 10
+	}
+
+	return func(buf []byte, t time.Time) []byte {
+		start := len(buf)
+		buf = appendFraction(buf, t.Nanosecond()/divisor, precision)
+		fill := byte('0')
+		if pad == '-' || pad == '_' {
+			for len(buf) > start+1 && buf[len(buf)-1] == '0' {
+				buf = buf[:len(buf)-1]
+			}
+			if pad == '-' {
+				return buf
+			}
+			fill = ' '
+		}
+		for i := len(buf) - start; i < width; i++ {
+			buf = append(buf, fill)
+		}
+		return buf
+	}
+}
+
+// appendFraction appends i as a zero padded number having the provided
+// number of digits.
+func appendFraction(buf []byte, i, precision int) []byte {
+	olen := len(buf)
+	for n := 0; n < precision; n++ {
+		buf = append(buf, '0')
+	}
+	for n := len(buf) - 1; n >= olen; n-- {
+		buf[n] = digits[i%10]
+		i /= 10
+	}
+	return buf
+}
+
+func appendRune(buf []byte, r rune) []byte {
+	if r < utf8.RuneSelf {
+		return append(buf, byte(r))
+	}
+	olen := len(buf)
+	buf = append(buf, 0, 0, 0, 0)             // grow buf large enough to accommodate largest possible UTF8 sequence
+	n := utf8.EncodeRune(buf[olen:olen+4], r) // encode rune into newly allocated buf space
+	return buf[:olen+n]                       // trim buf to actual size used by rune addition
 }
 
 // digits is two concatenated string slices that allow using an offset
@@ -246,138 +713,187 @@ const digits = "0123456789 123456789"
 
 // Dividend ÷ Divisor = Quotient
 
-func append2DigitsMin(buf *[]byte, i int) {
+func append2DigitsMin(buf []byte, i int) []byte {
 	quotient := i / 10
 	remainder := i % 10
 	if quotient > 0 {
-		*buf = append(*buf, digits[quotient])
+		buf = append(buf, digits[quotient])
 	}
-	*buf = append(*buf, digits[remainder])
+	return append(buf, digits[remainder])
 }
 
-func append2DigitsZero(buf *[]byte, i int) {
+func append2DigitsZero(buf []byte, i int) []byte {
 	quotient := i / 10
 	remainder := i % 10
-	*buf = append(*buf, digits[quotient])
-	*buf = append(*buf, digits[remainder])
+	buf = append(buf, digits[quotient])
+	return append(buf, digits[remainder])
 }
 
-func append2DigitsSpace(buf *[]byte, i int) {
+func append2DigitsSpace(buf []byte, i int) []byte {
 	quotient := i / 10
 	remainder := i % 10
-	*buf = append(*buf, digits[10+quotient])
-	*buf = append(*buf, digits[remainder])
+	buf = append(buf, digits[10+quotient])
+	return append(buf, digits[remainder])
 }
 
-func append3DigitsZero(buf *[]byte, i int) {
+func append3DigitsZero(buf []byte, i int) []byte {
 	// hundreds
 	quotient := i / 100
 	remainder := i % 100
-	*buf = append(*buf, digits[quotient])
+	buf = append(buf, digits[quotient])
 
 	// tens
 	quotient = remainder / 10
 	remainder %= 10
-	*buf = append(*buf, digits[quotient])
+	buf = append(buf, digits[quotient])
 
 	// ones
-	*buf = append(*buf, digits[remainder])
+	return append(buf, digits[remainder])
+}
+
+// appendYearDaySpace appends the day of the year padded on the left with
+// spaces to three characters, as the __2 layout element.
+func appendYearDaySpace(buf []byte, yday int) []byte {
+	if yday < 100 {
+		buf = append(buf, ' ')
+		if yday < 10 {
+			buf = append(buf, ' ')
+		}
+	}
+	return strconv.AppendInt(buf, int64(yday), 10)
 }
 
-func append4DigitsZero(buf *[]byte, i int) {
+func append4DigitsZero(buf []byte, i int) []byte {
 	// thousands
 	quotient := i / 1000
 	remainder := i % 1000
-	*buf = append(*buf, digits[quotient])
+	buf = append(buf, digits[quotient])
 
 	// hundreds
 	quotient = remainder / 100
 	remainder %= 100
-	*buf = append(*buf, digits[quotient])
+	buf = append(buf, digits[quotient])
 
 	// tens
 	quotient = remainder / 10
 	remainder %= 10
-	*buf = append(*buf, digits[quotient])
+	buf = append(buf, digits[quotient])
 
 	// ones
-	*buf = append(*buf, digits[remainder])
+	return append(buf, digits[remainder])
+}
+
+// appendYear appends year using at least four digits, preceded by a '-'
+// when it is negative, as the 2006 layout element of the time package
+// does. Years are numbered astronomically, so year 0 is 1 BC.
+func appendYear(buf []byte, year int) []byte {
+	if year < 0 {
+		buf = append(buf, '-')
+		year = -year
+	}
+	if year > 9999 {
+		return strconv.AppendInt(buf, int64(year), 10)
+	}
+	return append4DigitsZero(buf, year)
+}
+
+// appendCentury appends year without its last two digits, using at
+// least two digits, preceded by a '-' when year is negative, so that
+// the century followed by yearOfCentury is the result of appendYear.
+func appendCentury(buf []byte, year int) []byte {
+	if year < 0 {
+		buf = append(buf, '-')
+		year = -year
+	}
+	if year > 9999 {
+		return strconv.AppendInt(buf, int64(year/100), 10)
+	}
+	return append2DigitsZero(buf, year/100)
+}
+
+// yearOfCentury returns the last two digits of year, ignoring its sign,
+// as the 06 layout element of the time package does.
+func yearOfCentury(year int) int {
+	if year < 0 {
+		year = -year
+	}
+	return year % 100
 }
 
-func append6DigitsZero(buf *[]byte, i int) {
+func append6DigitsZero(buf []byte, i int) []byte {
 	// hundred-thousands
 	quotient := i / 100000
 	remainder := i % 100000
-	*buf = append(*buf, digits[quotient])
+	buf = append(buf, digits[quotient])
 
 	// ten-thousands
 	quotient = remainder / 10000
 	remainder = i % 10000
-	*buf = append(*buf, digits[quotient])
+	buf = append(buf, digits[quotient])
 
 	// thousands
 	quotient = remainder / 1000
 	remainder = i % 1000
-	*buf = append(*buf, digits[quotient])
+	buf = append(buf, digits[quotient])
 
 	// hundreds
 	quotient = remainder / 100
 	remainder %= 100
-	*buf = append(*buf, digits[quotient])
+	buf = append(buf, digits[quotient])
 
 	// tens
 	quotient = remainder / 10
 	remainder %= 10
-	*buf = append(*buf, digits[quotient])
+	buf = append(buf, digits[quotient])
 
 	// ones
-	*buf = append(*buf, digits[remainder])
+	return append(buf, digits[remainder])
 }
 
-func append9DigitsZero(buf *[]byte, i int) {
+func append9DigitsZero(buf []byte, i int) []byte {
 	// hundred-millions
 	//              123456789
 	quotient := i / 100000000
 	remainder := i % 100000000
-	*buf = append(*buf, digits[quotient])
+	buf = append(buf, digits[quotient])
 
 	// ten-millions
 	quotient = remainder / 10000000
 	remainder = i % 10000000
-	*buf = append(*buf, digits[quotient])
+	buf = append(buf, digits[quotient])
 
 	// millions
 	quotient = remainder / 1000000
 	remainder = i % 1000000
-	*buf = append(*buf, digits[quotient])
+	buf = append(buf, digits[quotient])
 
 	// hundred-thousands
 	quotient = remainder / 100000
 	remainder = i % 100000
-	*buf = append(*buf, digits[quotient])
+	buf = append(buf, digits[quotient])
 
 	// ten-thousands
 	quotient = remainder / 10000
 	remainder = i % 10000
-	*buf = append(*buf, digits[quotient])
+	buf = append(buf, digits[quotient])
 
 	// thousands
 	quotient = remainder / 1000
 	remainder = i % 1000
-	*buf = append(*buf, digits[quotient])
+	buf = append(buf, digits[quotient])
 
 	// hundreds
 	quotient = remainder / 100
 	remainder %= 100
-	*buf = append(*buf, digits[quotient])
+	buf = append(buf, digits[quotient])
 
 	// tens
 	quotient = remainder / 10
 	remainder %= 10
-	*buf = append(*buf, digits[quotient])
+	buf = append(buf, digits[quotient])
 
 	// ones
-	*buf = append(*buf, digits[remainder])
+	return append(buf, digits[remainder])
 }
 
 //                    0         1         2         3         4         5
@@ -386,22 +902,22 @@ const weekdaysLong = "SundayMondayTuesdayWednesdayThursdayFridaySaturday"
 
 var weekdaysLongIndices = []int{0, 6, 12, 19, 28, 36, 42, 50} // 50 is extra index to cover final entry in list
 
-func appendWeekdayShort(buf *[]byte, t time.Time) {
+func appendWeekdayShort(buf []byte, t time.Time) []byte {
 	// %a     The  abbreviated  name  of  the day of the week according to the
 	//        current locale.  (Calculated from tm_wday.)  (The specific names
 	//        used  in  the current locale can be obtained by calling nl_lang‐
 	//        info(3) with ABDAY_{1–7} as an argument.)
 	index := weekdaysLongIndices[t.Weekday()]
-	*buf = append(*buf, weekdaysLong[index:index+3]...)
+	return append(buf, weekdaysLong[index:index+3]...)
 }
 
-func appendWeekdayLong(buf *[]byte, t time.Time) {
+func appendWeekdayLong(buf []byte, t time.Time) []byte {
 	// %A     The full name of the day of the week according  to  the  current
 	//        locale.  (Calculated from tm_wday.)  (The specific names used in
 	//        the current locale can be  obtained  by  calling  nl_langinfo(3)
 	//        with DAY_{1–7} as an argument.)
 	index := t.Weekday()
-	*buf = append(*buf, weekdaysLong[weekdaysLongIndices[index]:weekdaysLongIndices[index+1]]...)
+	return append(buf, weekdaysLong[weekdaysLongIndices[index]:weekdaysLongIndices[index+1]]...)
 }
 
 //                  0         1         2         3         4         5         6         7
@@ -410,106 +926,101 @@ const monthsLong = "JanuaryFebruaryMarchAprilMayJuneJulyAugustSeptemberOctoberNo
 
 var monthsLongIndices = []int{0, 7, 15, 20, 25, 28, 32, 36, 42, 51, 58, 66, 74} // 74 is extra index to cover final entry in list
 
-func appendMonthShort(buf *[]byte, t time.Time) {
+func appendMonthShort(buf []byte, t time.Time) []byte {
 	// %b     The  abbreviated  month  name  according  to the current locale.
 	//        (Calculated from tm_mon.)  (The specific names used in the  cur‐
 	//        rent  locale  can be obtained by calling nl_langinfo(3) with AB‐
 	//        MON_{1–12} as an argument.)
 	index := monthsLongIndices[t.Month()-1]
-	*buf = append(*buf, monthsLong[index:index+3]...)
+	return append(buf, monthsLong[index:index+3]...)
 }
 
-func appendMonthLong(buf *[]byte, t time.Time) {
+func appendMonthLong(buf []byte, t time.Time) []byte {
 	// %B     The full month name according to the  current  locale.   (Calcu‐
 	//        lated from tm_mon.)  (The specific names used in the current lo‐
 	//        cale can be obtained by calling nl_langinfo(3)  with  MON_{1–12}
 	//        as an argument.)
 	index := t.Month() - 1
-	*buf = append(*buf, monthsLong[monthsLongIndices[index]:monthsLongIndices[index+1]]...)
+	return append(buf, monthsLong[monthsLongIndices[index]:monthsLongIndices[index+1]]...)
 }
 
-func appendC(buf *[]byte, t time.Time) {
+func appendC(buf []byte, t time.Time) []byte {
 	// %c     The  preferred  date and time representation for the current lo‐
 	//        cale.  (The specific format used in the current  locale  can  be
 	//        obtained  by  calling nl_langinfo(3) with D_T_FMT as an argument
 	//        for the %c conversion specification, and  with  ERA_D_T_FMT  for
 	//        the %Ec conversion specification.)  (In the POSIX locale this is
 	//        equivalent to %a %b %e %H:%M:%S %Y.)
-	appendWeekdayShort(buf, t)
-	*buf = append(*buf, ' ')
-	appendMonthShort(buf, t)
-	*buf = append(*buf, ' ')
+	buf = appendWeekdayShort(buf, t)
+	buf = append(buf, ' ')
+	buf = appendMonthShort(buf, t)
+	buf = append(buf, ' ')
 
-	append2DigitsSpace(buf, t.Day()) // appendE(buf, t)
+	buf = append2DigitsSpace(buf, t.Day()) // appendE(buf, t)
 
-	*buf = append(*buf, ' ')
-	appendTC(buf, t)
+	buf = append(buf, ' ')
+	buf = appendTC(buf, t)
 
-	*buf = append(*buf, ' ')
-	appendYC(buf, t)
+	buf = append(buf, ' ')
+	return appendYC(buf, t)
 }
 
-func appendCC(buf *[]byte, t time.Time) {
+func appendCC(buf []byte, t time.Time) []byte {
 	// %C     The century number (year/100) as a 2-digit  integer.  (SU)  (The
 	//        %EC  conversion  specification  corresponds  to  the name of the
 	//        era.)  (Calculated from tm_year.)
-	year := t.Year()
-	append2DigitsZero(buf, year/100)
+	return appendCentury(buf, t.Year())
 }
 
-func appendD(buf *[]byte, t time.Time) {
+func appendD(buf []byte, t time.Time) []byte {
 	// %d     The day of the month as a  decimal  number  (range  01  to  31).
 	//        (Calculated from tm_mday.)
-	append2DigitsZero(buf, t.Day())
+	return append2DigitsZero(buf, t.Day())
 }
 
-func appendDC(buf *[]byte, t time.Time) {
+func appendDC(buf []byte, t time.Time) []byte {
 	// %D     Equivalent  to  %m/%d/%y.  (Yecch—for Americans only.  Americans
 	//        should note that in other countries %d/%m/%y is  rather  common.
 	//        This  means that in international context this format is ambigu‐
 	//        ous and should not be used.) (SU)
 	year, month, day := t.Date()
 
-	append2DigitsZero(buf, int(month))
-	*buf = append(*buf, '/')
+	buf = append2DigitsZero(buf, int(month))
+	buf = append(buf, '/')
 
-	append2DigitsZero(buf, day)
-	*buf = append(*buf, '/')
+	buf = append2DigitsZero(buf, day)
+	buf = append(buf, '/')
 
-	append2DigitsZero(buf, year%100)
+	return append2DigitsZero(buf, yearOfCentury(year))
 }
 
-func appendE(buf *[]byte, t time.Time) {
+func appendE(buf []byte, t time.Time) []byte {
 	// %e     Like %d, the day of the month as a decimal number, but a leading
 	//        zero is replaced by a space. (SU) (Calculated from tm_mday.)
-	append2DigitsSpace(buf, t.Day())
+	return append2DigitsSpace(buf, t.Day())
 }
 
-// func appendEC(buf *[]byte, t time.Time) {
-// 	// %E     Modifier: use alternative ("era-based") format, see below. (SU)
-// }
-
-func appendFC(buf *[]byte, t time.Time) {
+func appendFC(buf []byte, t time.Time) []byte {
 	// %F     Equivalent to %Y-%m-%d (the ISO 8601 date format). (C99)
 	year, month, day := t.Date()
 
-	append4DigitsZero(buf, year)
-	*buf = append(*buf, '-')
+	buf = appendYear(buf, year)
+	buf = append(buf, '-')
 
-	append2DigitsZero(buf, int(month))
-	*buf = append(*buf, '-')
+	buf = append2DigitsZero(buf, int(month))
+	buf = append(buf, '-')
 
-	append2DigitsZero(buf, day)
+	return append2DigitsZero(buf, day)
 }
 
-func appendG(buf *[]byte, t time.Time) {
+func appendG(buf []byte, t time.Time) []byte {
 	// %g     Like %G, but without century,  that  is,  with  a  2-digit  year
 	//        (00–99). (TZ) (Calculated from tm_year, tm_yday, and tm_wday.)
 	year, _ := t.ISOWeek()
-	append2DigitsZero(buf, year%100)
+	return append2DigitsZero(buf, yearOfCentury(year))
 }
 
-func appendGC(buf *[]byte, t time.Time) {
+func appendGC(buf []byte, t time.Time) []byte {
 	// %G     The ISO 8601 week-based year (see NOTES) with century as a deci‐
 	//        mal number.  The 4-digit year corresponding to the ISO week num‐
 	//        ber  (see %V).  This has the same format and value as %Y, except
@@ -517,92 +1028,71 @@ func appendGC(buf *[]byte, t time.Time) {
 	//        year,  that year is used instead. (TZ) (Calculated from tm_year,
 	//        tm_yday, and tm_wday.)
 	year, _ := t.ISOWeek()
-	append4DigitsZero(buf, year)
+	return appendYear(buf, year)
 }
 
-func appendHC(buf *[]byte, t time.Time) {
+func appendHC(buf []byte, t time.Time) []byte {
 	// %H     The  hour as a decimal number using a 24-hour clock (range 00 to
 	//        23).  (Calculated from tm_hour.)
-	append2DigitsZero(buf, t.Hour())
+	return append2DigitsZero(buf, t.Hour())
 }
 
-func appendIC(buf *[]byte, t time.Time) {
+func appendIC(buf []byte, t time.Time) []byte {
 	// %I     The hour as a decimal number using a 12-hour clock (range 01  to
 	//        12).  (Calculated from tm_hour.)
-	hour := t.Hour()
-	if hour > 12 {
-		hour -= 12
+	hour := t.Hour() % 12
+	if hour == 0 {
+		hour = 12
 	}
-	append2DigitsZero(buf, hour)
+	return append2DigitsZero(buf, hour)
 }
 
-func appendJ(buf *[]byte, t time.Time) {
+func appendJ(buf []byte, t time.Time) []byte {
 	// %j     The  day  of  the  year  as a decimal number (range 001 to 366).
 	//        (Calculated from tm_yday.)
-	append3DigitsZero(buf, t.YearDay())
+	return append3DigitsZero(buf, t.YearDay())
 }
 
-func appendK(buf *[]byte, t time.Time) {
+func appendK(buf []byte, t time.Time) []byte {
 	// %k     The hour (24-hour clock) as a decimal number (range  0  to  23);
 	//        single  digits are preceded by a blank.  (See also %H.)  (Calcu‐
 	//        lated from tm_hour.)  (TZ)
-	append2DigitsSpace(buf, t.Hour())
+	return append2DigitsSpace(buf, t.Hour())
 }
 
-func appendL(buf *[]byte, t time.Time) {
+func appendL(buf []byte, t time.Time) []byte {
 	// %l     The hour (12-hour clock) as a decimal number (range  1  to  12);
 	//        single  digits are preceded by a blank.  (See also %I.)  (Calcu‐
 	//        lated from tm_hour.)  (TZ)
-	hour := t.Hour()
-	if hour > 12 {
-		hour -= 12
+	hour := t.Hour() % 12
+	if hour == 0 {
+		hour = 12
 	}
-	append2DigitsSpace(buf, hour)
+	return append2DigitsSpace(buf, hour)
 }
 
-func appendLMin(buf *[]byte, t time.Time) {
-	// only used to support time.Kitchen.
-	hour := t.Hour()
-	if hour > 12 {
-		hour -= 12
-	}
-	append2DigitsMin(buf, hour)
-}
-
-func appendM(buf *[]byte, t time.Time) {
+func appendM(buf []byte, t time.Time) []byte {
 	// %m     The month as a decimal number (range  01  to  12).   (Calculated
 	//        from tm_mon.)
-	append2DigitsZero(buf, int(t.Month()))
+	return append2DigitsZero(buf, int(t.Month()))
 }
 
-func appendMC(buf *[]byte, t time.Time) {
+func appendMC(buf []byte, t time.Time) []byte {
 	// %M     The  minute  as  a decimal number (range 00 to 59).  (Calculated
 	//        from tm_min.)
-	append2DigitsZero(buf, t.Minute())
+	return append2DigitsZero(buf, t.Minute())
 }
 
-func appendN(buf *[]byte, t time.Time) {
+func appendN(buf []byte, t time.Time) []byte {
 	// %n     A newline character. (SU)
-	*buf = append(*buf, '\n')
+	return append(buf, '\n')
 }
 
-func appendNC(buf *[]byte, t time.Time) {
-	append9DigitsZero(buf, t.Nanosecond())
+func appendNC(buf []byte, t time.Time) []byte {
+	return append9DigitsZero(buf, t.Nanosecond())
 }
 
-func appendMicro(buf *[]byte, t time.Time) {
-	append6DigitsZero(buf, t.Nanosecond()/1000)
-}
-
-func appendMilli(buf *[]byte, t time.Time) {
-	append3DigitsZero(buf, t.Nanosecond()/1000000)
-}
-
-// func appendOC(buf *[]byte, t time.Time) {
-// 	// %O     Modifier: use alternative numeric symbols, see below. (SU)
-// }
-
-func appendP(buf *[]byte, t time.Time) {
+func appendP(buf []byte, t time.Time) []byte {
 	// %p     Either "AM" or "PM" according to the given time  value,  or  the
 	//        corresponding  strings  for the current locale.  Noon is treated
 	//        as "PM" and midnight as "AM".  (Calculated from tm_hour.)   (The
@@ -611,24 +1101,31 @@ func appendP(buf *[]byte, t time.Time) {
 	//        AM_STR and PM_STR, respectively.)
 	hour := t.Hour()
 	if hour < 12 {
-		*buf = append(*buf, []byte("AM")...)
+		buf = append(buf, "AM"...)
 	} else {
-		*buf = append(*buf, []byte("PM")...)
+		buf = append(buf, "PM"...)
 	}
+	return buf
 }
 
-func appendPC(buf *[]byte, t time.Time) {
+func appendPC(buf []byte, t time.Time) []byte {
 	// %P     Like %p but in lowercase: "am" or "pm" or a corresponding string
 	//        for the current locale.  (Calculated from tm_hour.)  (GNU)
 	hour := t.Hour()
 	if hour < 12 {
-		*buf = append(*buf, []byte("am")...)
+		buf = append(buf, "am"...)
 	} else {
-		*buf = append(*buf, []byte("pm")...)
+		buf = append(buf, "pm"...)
 	}
+	return buf
 }
 
-func appendR(buf *[]byte, t time.Time) {
+func appendQ(buf []byte, t time.Time) []byte {
+	// %q     quarter of year (1..4)
+	return append(buf, byte((t.Month()+2)/3+'0'))
+}
+
+func appendR(buf []byte, t time.Time) []byte {
 	// %r     The time in a.m. or p.m. notation.  (SU)  (The  specific  format
 	//        used  in  the current locale can be obtained by calling nl_lang‐
 	//        info(3) with T_FMT_AMPM as an argument.)  (In the  POSIX  locale
@@ -639,115 +1136,112 @@ func appendR(buf *[]byte, t time.Time) {
 
 	if hour >= 12 {
 		pm = true
-		if hour > 12 {
-			hour -= 12
-		}
+		hour -= 12
+	}
+	if hour == 0 {
+		hour = 12
 	}
 
-	append2DigitsZero(buf, hour)
-	*buf = append(*buf, ':')
+	buf = append2DigitsZero(buf, hour)
+	buf = append(buf, ':')
 
-	append2DigitsZero(buf, minute)
-	*buf = append(*buf, ':')
+	buf = append2DigitsZero(buf, minute)
+	buf = append(buf, ':')
 
-	append2DigitsZero(buf, second)
-	*buf = append(*buf, ' ')
+	buf = append2DigitsZero(buf, second)
+	buf = append(buf, ' ')
 
 	if pm {
-		*buf = append(*buf, []byte("PM")...)
+		buf = append(buf, "PM"...)
 	} else {
-		*buf = append(*buf, []byte("AM")...)
+		buf = append(buf, "AM"...)
 	}
+	return buf
 }
 
-func appendRC(buf *[]byte, t time.Time) {
+func appendRC(buf []byte, t time.Time) []byte {
 	// %R     The  time  in  24-hour notation (%H:%M).  (SU) For a version in‐
 	//        cluding the seconds, see %T below.
 	hour, minute, _ := t.Clock()
 
-	append2DigitsZero(buf, hour)
-	*buf = append(*buf, ':')
-	append2DigitsZero(buf, minute)
+	buf = append2DigitsZero(buf, hour)
+	buf = append(buf, ':')
+	return append2DigitsZero(buf, minute)
 }
 
-func appendS(buf *[]byte, t time.Time) {
+func appendS(buf []byte, t time.Time) []byte {
 	// %s     The number of seconds since the Epoch, 1970-01-01 00:00:00 +0000
 	//        (UTC). (TZ) (Calculated from mktime(tm).)
-	*buf = strconv.AppendInt(*buf, t.Unix(), 10)
+	return strconv.AppendInt(buf, t.Unix(), 10)
 }
 
-func appendSC(buf *[]byte, t time.Time) {
+func appendSC(buf []byte, t time.Time) []byte {
 	// %S     The  second as a decimal number (range 00 to 60).  (The range is
 	//        up to 60 to allow for  occasional  leap  seconds.)   (Calculated
 	//        from tm_sec.)
-	append2DigitsZero(buf, t.Second())
+	return append2DigitsZero(buf, t.Second())
 }
 
-func appendT(buf *[]byte, t time.Time) {
+func appendT(buf []byte, t time.Time) []byte {
 	// %t     A tab character. (SU)
-	*buf = append(*buf, '\t')
+	return append(buf, '\t')
 }
 
-func appendTC(buf *[]byte, t time.Time) {
+func appendTC(buf []byte, t time.Time) []byte {
 	// %T     The time in 24-hour notation (%H:%M:%S).  (SU)
 	hour, minute, second := t.Clock()
 
-	append2DigitsZero(buf, hour)
-	*buf = append(*buf, ':')
+	buf = append2DigitsZero(buf, hour)
+	buf = append(buf, ':')
 
-	append2DigitsZero(buf, minute)
-	*buf = append(*buf, ':')
+	buf = append2DigitsZero(buf, minute)
+	buf = append(buf, ':')
 
-	append2DigitsZero(buf, second)
+	return append2DigitsZero(buf, second)
 }
 
-func appendU(buf *[]byte, t time.Time) {
+func appendU(buf []byte, t time.Time) []byte {
 	// %u     The  day of the week as a decimal, range 1 to 7, Monday being 1.
 	//        See also %w.  (Calculated from tm_wday.)  (SU)
 	if wd := t.Weekday(); wd > 0 {
-		*buf = append(*buf, byte(wd+'0'))
+		buf = append(buf, byte(wd+'0'))
 	} else {
-		*buf = append(*buf, '7')
-	}
-}
-
-// func appendUC(buf *[]byte, t time.Time) {
-// 	// %U     The week number of the current year as a decimal  number,  range
-// 	//        00  to  53,  starting  with the first Sunday as the first day of
-// 	//        week 01.  See also %V and  %W.   (Calculated  from  tm_yday  and
-// 	//        tm_wday.)
-// 	_, week := t.ISOWeek()
-// 	if week < 10 {
-// 		*buf = append(*buf, '0')
-// 	}
-// 	*buf = strconv.AppendInt(*buf, int64(week), 10)
-// }
-
-// func appendVC(buf *[]byte, t time.Time) {
-// 	// %V     The  ISO 8601  week  number (see NOTES) of the current year as a
-// 	//        decimal number, range 01 to 53, where week 1 is the  first  week
-// 	//        that  has  at least 4 days in the new year.  See also %U and %W.
-// 	//        (Calculated from tm_year, tm_yday, and tm_wday.)  (SU)
-// 	_, week := t.ISOWeek()
-// 	if week < 10 {
-// 		*buf = append(*buf, '0')
-// 	}
-// 	*buf = strconv.AppendInt(*buf, int64(week), 10)
-// }
-
-func appendW(buf *[]byte, t time.Time) {
+		buf = append(buf, '7')
+	}
+	return buf
+}
+
+func appendUC(buf []byte, t time.Time) []byte {
+	// %U     The week number of the current year as a decimal  number,  range
+	//        00  to  53,  starting  with the first Sunday as the first day of
+	//        week 01.  See also %V and  %W.   (Calculated  from  tm_yday  and
+	//        tm_wday.)
+	return append2DigitsZero(buf, (t.YearDay()+6-int(t.Weekday()))/7)
+}
+
+func appendVC(buf []byte, t time.Time) []byte {
+	// %V     The  ISO 8601  week  number (see NOTES) of the current year as a
+	//        decimal number, range 01 to 53, where week 1 is the  first  week
+	//        that  has  at least 4 days in the new year.  See also %U and %W.
+	//        (Calculated from tm_year, tm_yday, and tm_wday.)  (SU)
+	_, week := t.ISOWeek()
+	return append2DigitsZero(buf, week)
+}
+
+func appendW(buf []byte, t time.Time) []byte {
 	// %w     The day of the week as a decimal, range 0 to 6, Sunday being  0.
 	//        See also %u.  (Calculated from tm_wday.)
-	*buf = append(*buf, byte(t.Weekday()+'0'))
+	return append(buf, byte(t.Weekday()+'0'))
 }
 
-// func appendWC(buf *[]byte, t time.Time) {
-// 	// %W     The  week  number of the current year as a decimal number, range
-// 	//        00 to 53, starting with the first Monday as  the  first  day  of
-// 	//        week 01.  (Calculated from tm_yday and tm_wday.)
-// }
+func appendWC(buf []byte, t time.Time) []byte {
+	// %W     The  week  number of the current year as a decimal number, range
+	//        00 to 53, starting with the first Monday as  the  first  day  of
+	//        week 01.  (Calculated from tm_yday and tm_wday.)
+	return append2DigitsZero(buf, (t.YearDay()+6-(int(t.Weekday())+6)%7)/7)
+}
 
-func appendX(buf *[]byte, t time.Time) {
+func appendX(buf []byte, t time.Time) []byte {
 	// %x     The preferred date representation for the current locale without
 	//        the time.  (The specific format used in the current  locale  can
 	//        be  obtained by calling nl_langinfo(3) with D_FMT as an argument
@@ -755,91 +1249,121 @@ func appendX(buf *[]byte, t time.Time) {
 	//        %Ex  conversion  specification.)   (In  the POSIX locale this is
 	//        equivalent to %m/%d/%y.)
 	// 08/20/2021
-	appendDC(buf, t)
+	return appendDC(buf, t)
 }
 
-func appendXC(buf *[]byte, t time.Time) {
+func appendXC(buf []byte, t time.Time) []byte {
 	// %X     The preferred time representation for the current locale without
 	//        the  date.   (The specific format used in the current locale can
 	//        be obtained by calling nl_langinfo(3) with T_FMT as an  argument
 	//        for  the %X conversion specification, and with ERA_T_FMT for the
 	//        %EX conversion specification.)  (In the  POSIX  locale  this  is
 	//        equivalent to %H:%M:%S.)
-	appendTC(buf, t)
+	return appendTC(buf, t)
 }
 
-func appendY(buf *[]byte, t time.Time) {
+func appendY(buf []byte, t time.Time) []byte {
 	// %y     The year as a decimal number without a century (range 00 to 99).
 	//        (The %Ey conversion specification corresponds to the year  since
 	//        the  beginning of the era denoted by the %EC conversion specifi‐
 	//        cation.)  (Calculated from tm_year)
-	append2DigitsZero(buf, t.Year()%100)
+	return append2DigitsZero(buf, yearOfCentury(t.Year()))
 }
 
-func appendYC(buf *[]byte, t time.Time) {
+func appendYC(buf []byte, t time.Time) []byte {
 	// %Y     The year as a decimal number including the  century.   (The  %EY
 	//        conversion  specification  corresponds  to  the full alternative
 	//        year representation.)  (Calculated from tm_year)
-	append4DigitsZero(buf, t.Year())
+	return appendYear(buf, t.Year())
 }
 
-func appendZ(buf *[]byte, t time.Time) {
+func appendZ(buf []byte, t time.Time) []byte {
 	// %z     The +hhmm or -hhmm numeric  timezone  (that  is,  the  hour  and
 	//        minute offset from UTC). (SU)
 	_, offset := t.Zone()
-	hour := offset / 60
-	minute := offset % 60
-	if offset >= 0 {
-		*buf = append(*buf, '+')
+	return appendOffset(buf, offset)
+}
+
+// appendOffset appends the time zone offset, in seconds east of UTC, in
+// the +hhmm or -hhmm format of %z.
+func appendOffset(buf []byte, offset int) []byte {
+	// Like the time package, truncate an offset having seconds, such as
+	// a local mean time, to minutes.
+	if offset < 0 {
+		buf = append(buf, '-')
+		offset = -offset
 	} else {
-		*buf = append(*buf, '-')
+		buf = append(buf, '+')
 	}
-	append2DigitsZero(buf, hour)
-	append2DigitsZero(buf, minute)
+	minutes := offset / 60
+	buf = append2DigitsZero(buf, minutes/60)
+	return append2DigitsZero(buf, minutes%60)
 }
 
-func appendZC(buf *[]byte, t time.Time) {
+func appendZC(buf []byte, t time.Time) []byte {
 	// %Z     The timezone name or abbreviation.
 	name, _ := t.Zone()
-	*buf = append(*buf, name...)
+	return append(buf, name...)
 }
 
-func appendTZ(buf *[]byte, t time.Time) {
+// colonOffsetFormatters holds the formatters for %:z, %::z and %:::z,
+// indexed by their number of colons.
+var colonOffsetFormatters = [...]func([]byte, time.Time) []byte{
+	1: makeOffsetFormatter(false, true, true, false),
+	2: makeOffsetFormatter(false, true, true, true),
+	3: appendMinimalOffset,
+}
+
+// colonOffsetWidths holds the default field widths of %:z, %::z and
+// %:::z, indexed by their number of colons.
+var colonOffsetWidths = [...]int{1: 6, 2: 9, 3: 3}
+
+func appendMinimalOffset(buf []byte, t time.Time) []byte {
+	// %:::z  The numeric timezone with ':' to the necessary precision,
+	//        such as -04, +05:30, or -04:56:02.
 	_, offset := t.Zone()
-	if offset == 0 {
-		*buf = append(*buf, 'Z')
+	if offset < 0 {
+		buf = append(buf, '-')
+		offset = -offset
 	} else {
-		hour := offset / 60
-		minute := offset % 60
-		if offset < 0 {
-			*buf = append(*buf, '-')
+		buf = append(buf, '+')
+	}
+	buf = append2DigitsZero(buf, offset/3600)
+	if offset%3600 != 0 {
+		buf = append(buf, ':')
+		buf = append2DigitsZero(buf, offset/60%60)
+		if offset%60 != 0 {
+			buf = append(buf, ':')
+			buf = append2DigitsZero(buf, offset%60)
 		}
-		append2DigitsZero(buf, hour)
-		*buf = append(*buf, ':')
-		append2DigitsZero(buf, minute)
 	}
+	return buf
 }
 
-func appendPercent(buf *[]byte, t time.Time) {
+// appendKC formats %K, the time zone offset of RFC 3339: Z for UTC, and
+// otherwise +hh:mm or -hh:mm.
+var appendKC = makeOffsetFormatter(true, true, true, false)
+
+func appendPercent(buf []byte, t time.Time) []byte {
 	// %%     A literal '%' character.
-	*buf = append(*buf, '%')
+	return append(buf, '%')
 }
 
-func appendPlus(buf *[]byte, t time.Time) {
+func appendPlus(buf []byte, t time.Time) []byte {
 	// %+     The  date  and  time  in  date(1) format. (TZ) (Not supported in
 	//        glibc2.)
 	// "%a %b %e %T %p %Z %Y"
-	appendWeekdayShort(buf, t)
-	*buf = append(*buf, ' ')
-	appendMonthShort(buf, t)
-	*buf = append(*buf, ' ')
-	appendE(buf, t)
-	*buf = append(*buf, ' ')
-	appendTC(buf, t)
-	*buf = append(*buf, ' ')
-	appendP(buf, t)
-	*buf = append(*buf, ' ')
-	appendZC(buf, t)
-	*buf = append(*buf, ' ')
-	appendYC(buf, t)
+	buf = appendWeekdayShort(buf, t)
+	buf = append(buf, ' ')
+	buf = appendMonthShort(buf, t)
+	buf = append(buf, ' ')
+	buf = appendE(buf, t)
+	buf = append(buf, ' ')
+	buf = appendTC(buf, t)
+	buf = append(buf, ' ')
+	buf = appendP(buf, t)
+	buf = append(buf, ' ')
+	buf = appendZC(buf, t)
+	buf = append(buf, ' ')
+	return appendYC(buf, t)
 }
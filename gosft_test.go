@@ -1,6 +1,14 @@
 package gosft
 
 import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -20,7 +28,7 @@ func TestAppend2Digits(t *testing.T) {
 		for _, c := range tests {
 			t.Run(c.want, func(t *testing.T) {
 				var buf []byte
-				append2DigitsMin(&buf, c.value)
+				buf = append2DigitsMin(buf, c.value)
 				if got, want := string(buf), c.want; got != want {
 					t.Errorf("GOT: %q; WANT: %q", got, want)
 				}
@@ -41,7 +49,7 @@ func TestAppend2Digits(t *testing.T) {
 		for _, c := range tests {
 			t.Run(c.want, func(t *testing.T) {
 				var buf []byte
-				append2DigitsZero(&buf, c.value)
+				buf = append2DigitsZero(buf, c.value)
 				if got, want := string(buf), c.want; got != want {
 					t.Errorf("GOT: %q; WANT: %q", got, want)
 				}
@@ -62,7 +70,7 @@ func TestAppend2Digits(t *testing.T) {
 		for _, c := range tests {
 			t.Run(c.want, func(t *testing.T) {
 				var buf []byte
-				append2DigitsSpace(&buf, c.value)
+				buf = append2DigitsSpace(buf, c.value)
 				if got, want := string(buf), c.want; got != want {
 					t.Errorf("GOT: %q; WANT: %q", got, want)
 				}
@@ -88,7 +96,7 @@ func TestAppend3Digits(t *testing.T) {
 		for _, c := range tests {
 			t.Run(c.want, func(t *testing.T) {
 				var buf []byte
-				append3DigitsZero(&buf, c.value)
+				buf = append3DigitsZero(buf, c.value)
 				if got, want := string(buf), c.want; got != want {
 					t.Errorf("GOT: %q; WANT: %q", got, want)
 				}
@@ -97,56 +105,56 @@ func TestAppend3Digits(t *testing.T) {
 	})
 }
 
-func append4DigitsMin(buf *[]byte, i int) {
+func append4DigitsMin(buf []byte, i int) []byte {
 	var foo bool
 	if i >= 1000 {
-		*buf = append(*buf, digits[i/1000])
+		buf = append(buf, digits[i/1000])
 		i %= 1000
 		foo = true
 	}
 
 	if i >= 100 {
-		*buf = append(*buf, digits[i/100])
+		buf = append(buf, digits[i/100])
 		i %= 100
 		foo = true
 	} else if foo {
-		*buf = append(*buf, '0')
+		buf = append(buf, '0')
 	}
 
 	if i >= 10 {
-		*buf = append(*buf, digits[i/10])
+		buf = append(buf, digits[i/10])
 		i %= 10
 		foo = true
 	} else if foo {
-		*buf = append(*buf, '0')
+		buf = append(buf, '0')
 	}
 
-	*buf = append(*buf, digits[i])
+	return append(buf, digits[i])
 }
 
-func append4DigitsSpace(buf *[]byte, i int) {
+func append4DigitsSpace(buf []byte, i int) []byte {
 	offset := 10
 
 	// thousands
-	*buf = append(*buf, digits[offset+i/1000])
+	buf = append(buf, digits[offset+i/1000])
 	if i >= 1000 {
 		offset = 0
 	}
 	i %= 1000
 
 	// hundreds
-	*buf = append(*buf, digits[offset+i/100])
+	buf = append(buf, digits[offset+i/100])
 	if i >= 100 {
 		offset = 0
 	}
 	i %= 100
 
 	// tens
-	*buf = append(*buf, digits[offset+i/10])
+	buf = append(buf, digits[offset+i/10])
 	i %= 10
 
 	// ones
-	*buf = append(*buf, digits[i])
+	return append(buf, digits[i])
 }
 
 func TestAppend4Digits(t *testing.T) {
@@ -167,7 +175,7 @@ func TestAppend4Digits(t *testing.T) {
 		for _, c := range tests {
 			t.Run(c.want, func(t *testing.T) {
 				var buf []byte
-				append4DigitsMin(&buf, c.value)
+				buf = append4DigitsMin(buf, c.value)
 				if got, want := string(buf), c.want; got != want {
 					t.Errorf("GOT: %q; WANT: %q", got, want)
 				}
@@ -191,7 +199,7 @@ func TestAppend4Digits(t *testing.T) {
 		for _, c := range tests {
 			t.Run(c.want, func(t *testing.T) {
 				var buf []byte
-				append4DigitsZero(&buf, c.value)
+				buf = append4DigitsZero(buf, c.value)
 				if got, want := string(buf), c.want; got != want {
 					t.Errorf("GOT: %q; WANT: %q", got, want)
 				}
@@ -215,7 +223,7 @@ func TestAppend4Digits(t *testing.T) {
 		for _, c := range tests {
 			t.Run(c.want, func(t *testing.T) {
 				var buf []byte
-				append4DigitsSpace(&buf, c.value)
+				buf = append4DigitsSpace(buf, c.value)
 				if got, want := string(buf), c.want; got != want {
 					t.Errorf("GOT: %q; WANT: %q", got, want)
 				}
@@ -239,7 +247,7 @@ func TestAppend6Digits(t *testing.T) {
 	for _, c := range tests {
 		t.Run(c.want, func(t *testing.T) {
 			var buf []byte
-			append6DigitsZero(&buf, c.value)
+			buf = append6DigitsZero(buf, c.value)
 			if got, want := string(buf), c.want; got != want {
 				t.Errorf("GOT: %q; WANT: %q", got, want)
 			}
@@ -262,7 +270,7 @@ func TestAppend9Digits(t *testing.T) {
 	for _, c := range tests {
 		t.Run(c.want, func(t *testing.T) {
 			var buf []byte
-			append9DigitsZero(&buf, c.value)
+			buf = append9DigitsZero(buf, c.value)
 			if got, want := string(buf), c.want; got != want {
 				t.Errorf("GOT: %q; WANT: %q", got, want)
 			}
@@ -303,6 +311,7 @@ func TestFormatter(t *testing.T) {
 		// {"%O", "TODO"}, // Modifier: use alternative numeric symbols.
 		{"%p", "AM"},          // Either "AM" or "PM" according to the given time value.
 		{"%P", "am"},          // Either "am" or "pm" according to the given time value.
+		{"%q", "1"},           // The quarter of the year (1..4).
 		{"%r", "03:04:05 AM"}, // The time in a.m. or p.m. notation. Equivalent to `%I:%M:%S %p`.
 		{"%R", "03:04"},       // The time in 24-hour notation. Equivalent to `%H:%M`
 		{"%s", "1136171045"},  // The number of seconds since the Epoch, 1970-01-01 00:00:00 +0000 UTC.
@@ -310,16 +319,16 @@ func TestFormatter(t *testing.T) {
 		{"%t", "\t"},          // A tab character.
 		{"%T", "03:04:05"},    // The time in 24-hour notation. Equivalent to `%H:%M:%S`.
 		{"%u", "1"},           // The day of the week, (1..7); 1 is Monday.
-		// {"%U", "TODO"}, // The week number of the current year as a decimal number.
-		// {"%V", "TODO"}, // The ISO 8601 week number of the current year as a decimal number.
-		{"%w", "1"}, // The day of the week as a decimal, (0..6); 0 is Sunday.
-		// {"%W", "TODO"}, // The week number of the current year as a decimal number.
-		{"%x", "01/02/06"}, // Equivalent to `%m/%d/%y`
-		{"%X", "03:04:05"}, // Equivalent to `%H:%M:%S`
-		{"%y", "06"},       // The year as a decimal number without a century (00..99).
-		{"%Y", "2006"},     // The year as a decimal number including the century.
-		{"%z", "+0000"},    // The ++hhmm or -hhmm numeric timezone.
-		{"%Z", "UTC"},      // The timezone name or abbreviation.
+		{"%U", "01"},          // The week number of the current year, (00..53); weeks start on Sunday.
+		{"%V", "01"},          // The ISO 8601 week number of the current year, (01..53).
+		{"%w", "1"},           // The day of the week as a decimal, (0..6); 0 is Sunday.
+		{"%W", "01"},          // The week number of the current year, (00..53); weeks start on Monday.
+		{"%x", "01/02/06"},    // Equivalent to `%m/%d/%y`
+		{"%X", "03:04:05"},    // Equivalent to `%H:%M:%S`
+		{"%y", "06"},          // The year as a decimal number without a century (00..99).
+		{"%Y", "2006"},        // The year as a decimal number including the century.
+		{"%z", "+0000"},       // The ++hhmm or -hhmm numeric timezone.
+		{"%Z", "UTC"},         // The timezone name or abbreviation.
 		{"%+", "Mon Jan  2 03:04:05 AM UTC 2006"}, // The date and time in date(1) format.
 		{"%%", "%"}, // A % character.
 
@@ -350,6 +359,303 @@ func TestFormatter(t *testing.T) {
 	}
 }
 
+func TestTwelveHourClock(t *testing.T) {
+	tests := []struct {
+		hour int
+		want string
+	}{
+		{0, "12|12|12:04:05 AM|12"}, // Midnight is 12 AM, not 00 AM.
+		{1, "01| 1|01:04:05 AM|1"},
+		{11, "11|11|11:04:05 AM|11"},
+		{12, "12|12|12:04:05 PM|12"}, // Noon is 12 PM.
+		{13, "01| 1|01:04:05 PM|1"},
+		{23, "11|11|11:04:05 PM|11"},
+	}
+
+	tf, err := New("%I|%l|%r|%-I")
+	ensureError(t, err, nil)
+
+	for _, c := range tests {
+		when := time.Date(2006, time.January, 2, c.hour, 4, 5, 0, time.UTC)
+		if got, want := tf.Format(when), c.want; got != want {
+			t.Errorf("%d: GOT: %q; WANT: %q", c.hour, got, want)
+		}
+	}
+}
+
+func TestFlags(t *testing.T) {
+	when := time.Date(2006, time.January, 2, 3, 4, 5, 12300000, time.UTC)
+
+	// Expected values were generated by GNU date(1).
+	tests := []struct {
+		format, want string
+	}{
+		{"%1d", "2"},
+		{"%-d", "2"},
+		{"%_d", " 2"},
+		{"%0e", "02"},
+		{"%5d", "00002"},
+		{"%-5d", "2"},
+		{"%_5d", "    2"},
+		{"%05e", "00002"},
+		{"%^a", "MON"},
+		{"%#a", "MON"},
+		{"%#p", "am"},
+		{"%#Z", "utc"},
+		{"%^B", "JANUARY"},
+		{"%10Y", "0000002006"},
+		{"%_10Y", "      2006"},
+		{"%-10Y", "2006"},
+		{"%010Y", "0000002006"},
+		{"%10a", "       Mon"},
+		{"%010a", "0000000Mon"},
+		{"%-10a", "Mon"},
+		{"%^c", "MON JAN  2 03:04:05 2006"},
+		{"%_20F", "          2006-01-02"},
+		{"%020F", "00000000002006-01-02"},
+		{"%-20F", "2006-01-02"},
+		{"%^10b", "       JAN"},
+		{"%3j", "002"},
+		{"%-j", "2"},
+		{"%_j", "  2"},
+		{"%5s", "1136171045"},
+		{"%_5z", "   +0"},
+		{"%-5z", "+0"},
+		{"%05z", "+0000"},
+		{"%10z", "+000000000"},
+		{"%5n", "    \n"},
+		{"%-I:%M%p", "3:04AM"},
+		{"%-k", "3"},
+		{"%3N", "012"},
+		{"%-3N", "012"},
+		{"%-N", "0123"},
+		{"%-9N", "0123"},
+		{"%-2N", "01"},
+		{"%1N", "0"},
+		{"%_6N", "0123  "},
+		{"%12N", "012300000000"},
+	}
+
+	for _, c := range tests {
+		t.Run(c.format, func(t *testing.T) {
+			tf, err := New(c.format)
+			ensureError(t, err, nil)
+
+			if got, want := tf.Format(when), c.want; got != want {
+				t.Errorf("GOT: %q; WANT: %q", got, want)
+			}
+		})
+	}
+
+	t.Run("negative", func(t *testing.T) {
+		when := time.Date(1969, time.December, 31, 23, 59, 55, 0, time.UTC)
+
+		tf, err := New("[%5s] [%_5s] [%05s] [%-5s]")
+		ensureError(t, err, nil)
+
+		if got, want := tf.Format(when), "[-0005] [   -5] [-0005] [-5]"; got != want {
+			t.Errorf("GOT: %q; WANT: %q", got, want)
+		}
+	})
+
+	t.Run("locale", func(t *testing.T) {
+		locale, _ := LookupLocale("fr_FR")
+
+		tf, err := NewWithLocale("%^a|%^B|%12B|%-e", locale)
+		ensureError(t, err, nil)
+
+		when := time.Date(2006, time.February, 2, 3, 4, 5, 0, time.UTC)
+		if got, want := tf.Format(when), "JEU.|FÉVRIER|     février|2"; got != want {
+			t.Errorf("GOT: %q; WANT: %q", got, want)
+		}
+	})
+
+	t.Run("trimmed fraction", func(t *testing.T) {
+		tf, err := New("%S.%-3N|%S.%-N|%S.%_3N|%S.%6N")
+		ensureError(t, err, nil)
+
+		tests := []struct {
+			nanosecond int
+			want       string
+		}{
+			{0, "05.0|05.0|05.0  |05.000000"},
+			{100000000, "05.1|05.1|05.1  |05.100000"},
+			{120000000, "05.12|05.12|05.12 |05.120000"},
+			{123456789, "05.123|05.123456789|05.123|05.123456"},
+			{999999999, "05.999|05.999999999|05.999|05.999999"},
+		}
+
+		for _, c := range tests {
+			when := time.Date(2006, time.January, 2, 3, 4, 5, c.nanosecond, time.UTC)
+			if got, want := tf.Format(when), c.want; got != want {
+				t.Errorf("GOT: %q; WANT: %q", got, want)
+			}
+		}
+	})
+
+	t.Run("width too large", func(t *testing.T) {
+		_, err := New("%1025d")
+		ensureError(t, err, errors.New("cannot use field width greater than 1024 at index 4"))
+	})
+}
+
+func TestNumericOffsets(t *testing.T) {
+	tests := []struct {
+		name   string
+		offset int
+		want   string
+	}{
+		{"UTC", 0, "+0000|+0|UTC"},
+		{"NPT", 5*60*60 + 45*60, "+0545|+545|NPT"},
+		{"MART", -(9*60*60 + 30*60), "-0930|-930|MART"},
+		{"EDT", -4 * 60 * 60, "-0400|-400|EDT"},
+		{"LMT", -(4*60*60 + 56*60 + 2), "-0456|-456|LMT"},
+		{"LMT", 4*60*60 + 51*60 + 40, "+0451|+451|LMT"},
+		{"LINT", 14 * 60 * 60, "+1400|+1400|LINT"},
+		{"", -12 * 60 * 60, "-1200|-1200|"},
+		{"", -59, "-0000|-0|"},
+	}
+
+	tf, err := New("%z|%-z|%Z")
+	ensureError(t, err, nil)
+
+	offsets, err := New("%z|%-z")
+	ensureError(t, err, nil)
+
+	for _, c := range tests {
+		when := time.Date(2006, time.January, 2, 3, 4, 5, 0, time.FixedZone(c.name, c.offset))
+		t.Run(c.want, func(t *testing.T) {
+			if got, want := tf.Format(when), c.want; got != want {
+				t.Errorf("GOT: %q; WANT: %q", got, want)
+			}
+			if got, want := string(offsets.AppendUnix(nil, when.Unix(), c.offset)), c.want[:strings.LastIndexByte(c.want, '|')]; got != want {
+				t.Errorf("AppendUnix: GOT: %q; WANT: %q", got, want)
+			}
+		})
+	}
+}
+
+// TestZoneinfo compares the time zone offsets and names formatted for
+// each zone of the system's zoneinfo database with those formatted by
+// the time package.
+func TestZoneinfo(t *testing.T) {
+	var root string
+	for _, dir := range []string{"/usr/share/zoneinfo", "/usr/share/lib/zoneinfo", "/usr/lib/locale/TZ", "/etc/zoneinfo"} {
+		if fi, err := os.Stat(dir); err == nil && fi.IsDir() {
+			root = dir
+			break
+		}
+	}
+	if root == "" {
+		t.Skip("cannot find zoneinfo database")
+	}
+
+	tf, err := New("%z|%:z|%::z|%:::z|%K|%Z")
+	ensureError(t, err, nil)
+
+	// Include times before standard time was adopted, when most zones
+	// observed a local mean time having an offset with seconds.
+	times := []time.Time{
+		time.Date(1800, time.January, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(1944, time.July, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(1970, time.January, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, time.January, 15, 12, 0, 0, 0, time.UTC),
+		time.Date(2021, time.July, 15, 12, 0, 0, 0, time.UTC),
+	}
+
+	var zones int
+	err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		name, _ := filepath.Rel(root, path)
+		if info.IsDir() {
+			if name == "posix" || name == "right" {
+				return filepath.SkipDir // duplicates of the other zones
+			}
+			return nil
+		}
+		loc, err := time.LoadLocation(name)
+		if err != nil {
+			return nil // not a zone, such as zone.tab
+		}
+		zones++
+
+		for _, when := range times {
+			when = when.In(loc)
+			if _, offset := when.Zone(); offset < 0 && offset > -60 {
+				// The time package formats the sign of an offset of
+				// less than a minute west of UTC as if it were east.
+				continue
+			}
+
+			// The time package has no layout element for %:::z.
+			minimal := when.Format("-07:00:00")
+			if strings.HasSuffix(minimal, ":00") {
+				minimal = strings.TrimSuffix(minimal[:len(minimal)-3], ":00")
+			}
+			want := when.Format("-0700|-07:00|-07:00:00|") + minimal + when.Format("|Z07:00|")
+			zone, _ := when.Zone()
+			want += zone
+
+			if got := tf.Format(when); got != want {
+				t.Errorf("%s: %v: GOT: %q; WANT: %q", name, when, got, want)
+			}
+		}
+		return nil
+	})
+	ensureError(t, err, nil)
+
+	if zones == 0 {
+		t.Skip("cannot find zones in zoneinfo database")
+	}
+}
+
+func TestColonOffsets(t *testing.T) {
+	tests := []struct {
+		offset int
+		want   string
+	}{
+		{0, "+00:00|+00:00:00|+00|Z"},
+		{5*60*60 + 45*60, "+05:45|+05:45:00|+05:45|+05:45"},
+		{-(9*60*60 + 30*60), "-09:30|-09:30:00|-09:30|-09:30"},
+		{-4 * 60 * 60, "-04:00|-04:00:00|-04|-04:00"},
+		{-(4*60*60 + 56*60 + 2), "-04:56|-04:56:02|-04:56:02|-04:56"},
+		{14 * 60 * 60, "+14:00|+14:00:00|+14|+14:00"},
+	}
+
+	tf, err := New("%:z|%::z|%:::z|%K")
+	ensureError(t, err, nil)
+
+	for _, c := range tests {
+		when := time.Date(2006, time.January, 2, 3, 4, 5, 0, time.FixedZone("", c.offset))
+		t.Run(c.want, func(t *testing.T) {
+			if got, want := tf.Format(when), c.want; got != want {
+				t.Errorf("GOT: %q; WANT: %q", got, want)
+			}
+		})
+	}
+
+	t.Run("flags", func(t *testing.T) {
+		tf, err := New("[%-:z] [%_:z] [%8:z] [%_:::z]")
+		ensureError(t, err, nil)
+
+		when := time.Date(2006, time.January, 2, 3, 4, 5, 0, time.FixedZone("", 5*60*60))
+		if got, want := tf.Format(when), "[+5:00] [ +5:00] [+0005:00] [ +5]"; got != want {
+			t.Errorf("GOT: %q; WANT: %q", got, want)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := New("%:d")
+		ensureError(t, err, errors.New("cannot use colons with format verb 'd' at index 2"))
+
+		_, err = New("%::::z")
+		ensureError(t, err, errors.New("cannot use colons with format verb ':' at index 4"))
+	})
+}
+
 func TestWeekdays(t *testing.T) {
 	tests := []struct {
 		day         int
@@ -423,6 +729,103 @@ func TestMonths(t *testing.T) {
 	}
 }
 
+func TestWeekNumbers(t *testing.T) {
+	// Expected values were generated by GNU date(1).
+	tests := []struct {
+		year  int
+		month time.Month
+		day   int
+		want  string
+	}{
+		{2005, time.January, 1, "Sat 00 53 00 2004"},
+		{2005, time.January, 2, "Sun 01 53 00 2004"},
+		{2005, time.January, 3, "Mon 01 01 01 2005"},
+		{2007, time.December, 31, "Mon 52 01 53 2008"},
+		{2008, time.December, 29, "Mon 52 01 52 2009"},
+		{2009, time.December, 31, "Thu 52 53 52 2009"},
+		{2010, time.January, 3, "Sun 01 53 00 2009"},
+		{2012, time.January, 1, "Sun 01 52 00 2011"},
+		{2021, time.January, 3, "Sun 01 53 00 2020"},
+		{2021, time.January, 4, "Mon 01 01 01 2021"},
+	}
+
+	tf, err := New("%a %U %V %W %G")
+	ensureError(t, err, nil)
+
+	for _, c := range tests {
+		when := time.Date(c.year, c.month, c.day, 3, 4, 5, 0, time.UTC)
+		t.Run(when.Format("2006-01-02"), func(t *testing.T) {
+			if got, want := tf.Format(when), c.want; got != want {
+				t.Errorf("GOT: %q; WANT: %q", got, want)
+			}
+		})
+	}
+}
+
+func TestYears(t *testing.T) {
+	tests := []struct {
+		year int
+		want string
+	}{
+		{-12345, "-12345|-123|45|-12345|45|-12345-03-15|03/15/45|-12345|-12345"},
+		{-44, "-0044|-00|44|-0044|44|-0044-03-15|03/15/44|-44|-00044"},
+		{0, "0000|00|00|0000|00|0000-03-15|03/15/00|0|000000"},
+		{44, "0044|00|44|0044|44|0044-03-15|03/15/44|44|000044"},
+		{12345, "12345|123|45|12345|45|12345-03-15|03/15/45|12345|012345"},
+	}
+
+	tf, err := New("%Y|%C|%y|%G|%g|%F|%D|%-Y|%6Y")
+	ensureError(t, err, nil)
+
+	for _, c := range tests {
+		when := time.Date(c.year, time.March, 15, 3, 4, 5, 0, time.UTC)
+		t.Run(strconv.Itoa(c.year), func(t *testing.T) {
+			if got, want := tf.Format(when), c.want; got != want {
+				t.Errorf("GOT: %q; WANT: %q", got, want)
+			}
+			if got, want := string(tf.AppendUnix(nil, when.Unix(), 0)), c.want; got != want {
+				t.Errorf("GOT: %q; WANT: %q", got, want)
+			}
+		})
+	}
+
+	t.Run("consistency", func(t *testing.T) {
+		// The century followed by the year of the century is the
+		// year, which is formatted as the time package does.
+		tf, err := New("%C%y|%Y|%Y-%m-%d|%y")
+		ensureError(t, err, nil)
+		cf, err := NewCompat("2006|2006|2006-01-02|06")
+		ensureError(t, err, nil)
+
+		for year := -100100; year <= 100100; year += 37 {
+			when := time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC)
+			want := when.Format("2006|2006|2006-01-02|06")
+			if got := tf.Format(when); got != want {
+				t.Fatalf("GOT: %q; WANT: %q", got, want)
+			}
+			if got := cf.Format(when); got != want {
+				t.Fatalf("GOT: %q; WANT: %q", got, want)
+			}
+		}
+	})
+
+	t.Run("size", func(t *testing.T) {
+		tf, err := New("%c %G")
+		ensureError(t, err, nil)
+
+		for _, year := range []int{-999999999, -10000, -1, 0, 9999, 10000, 999999999} {
+			for _, when := range []time.Time{
+				time.Date(year, time.January, 1, 0, 0, 0, 0, time.FixedZone("", 14*60*60)),
+				time.Date(year, time.December, 31, 23, 59, 59, 0, time.FixedZone("", -12*60*60)),
+			} {
+				if got, want := tf.sizeFor(when), len(tf.Format(when)); got < want {
+					t.Errorf("%v: GOT: %d; WANT: >= %d", when, got, want)
+				}
+			}
+		}
+	})
+}
+
 func TestCompatibility(t *testing.T) {
 	// Use the same date-time stamp that Go standard library uses,
 	// namely 2006-01-02T15:04:05Z07:00
@@ -458,6 +861,92 @@ func TestCompatibility(t *testing.T) {
 	}
 }
 
+func TestWrite(t *testing.T) {
+	when := time.Date(2009, time.February, 5, 5, 0, 57, 12345600, time.UTC)
+
+	tf, err := New("%F %T.%3N")
+	ensureError(t, err, nil)
+
+	t.Run("writer", func(t *testing.T) {
+		var bb bytes.Buffer
+		n, err := tf.Write(&bb, when)
+		ensureError(t, err, nil)
+		if got, want := bb.String(), "2009-02-05 05:00:57.012"; got != want || n != len(want) {
+			t.Errorf("GOT: %q (%d); WANT: %q", got, n, want)
+		}
+	})
+
+	t.Run("bufio", func(t *testing.T) {
+		var bb bytes.Buffer
+		bw := bufio.NewWriterSize(&bb, 16)
+		for i := 0; i < 3; i++ {
+			_, err := tf.Write(bw, when)
+			ensureError(t, err, nil)
+			ensureError(t, bw.WriteByte('\n'), nil)
+		}
+		ensureError(t, bw.Flush(), nil)
+		want := "2009-02-05 05:00:57.012\n2009-02-05 05:00:57.012\n2009-02-05 05:00:57.012\n"
+		if got := bb.String(); got != want {
+			t.Errorf("GOT: %q; WANT: %q", got, want)
+		}
+	})
+
+	t.Run("fprintf", func(t *testing.T) {
+		var bb bytes.Buffer
+		n, err := tf.Fprintf(&bb, when, " [%s] %d\n", "info", 42)
+		ensureError(t, err, nil)
+		if got, want := bb.String(), "2009-02-05 05:00:57.012 [info] 42\n"; got != want || n != len(want) {
+			t.Errorf("GOT: %q (%d); WANT: %q", got, n, want)
+		}
+	})
+
+	t.Run("allocations", func(t *testing.T) {
+		bw := bufio.NewWriterSize(io.Discard, 4096)
+		if allocs := testing.AllocsPerRun(100, func() { _, _ = tf.Write(bw, when) }); allocs != 0 {
+			t.Errorf("GOT: %v allocations; WANT: 0", allocs)
+		}
+		if allocs := testing.AllocsPerRun(100, func() { _, _ = tf.Write(io.Discard, when) }); allocs != 0 {
+			t.Errorf("GOT: %v allocations; WANT: 0", allocs)
+		}
+	})
+}
+
+func TestIn(t *testing.T) {
+	tf, err := New("%F %T %Z %z")
+	ensureError(t, err, nil)
+
+	est := time.FixedZone("EST", -5*60*60)
+	in := tf.In(est)
+
+	when := time.Date(2009, time.February, 5, 5, 0, 57, 0, time.FixedZone("CET", 60*60))
+	want := "2009-02-04 23:00:57 EST -0500"
+
+	if got := in.Format(when); got != want {
+		t.Errorf("Format: GOT: %q; WANT: %q", got, want)
+	}
+	if got := string(in.Append(nil, when.UTC())); got != want {
+		t.Errorf("Append: GOT: %q; WANT: %q", got, want)
+	}
+	if got := string(in.AppendUnix(nil, when.Unix(), 0)); got != want {
+		t.Errorf("AppendUnix: GOT: %q; WANT: %q", got, want)
+	}
+	if got := NewCachingFormatter(in).Format(when); got != want {
+		t.Errorf("CachingFormatter: GOT: %q; WANT: %q", got, want)
+	}
+
+	var bb bytes.Buffer
+	_, err = in.Write(&bb, when)
+	ensureError(t, err, nil)
+	if got := bb.String(); got != want {
+		t.Errorf("Write: GOT: %q; WANT: %q", got, want)
+	}
+
+	// The original formatter continues to use the location of each time.
+	if got, want := tf.Format(when), "2009-02-05 05:00:57 CET +0100"; got != want {
+		t.Errorf("GOT: %q; WANT: %q", got, want)
+	}
+}
+
 func BenchmarkCompatibility(b *testing.B) {
 	var err error
 	var foo string
@@ -516,6 +1005,15 @@ func BenchmarkCompatibility(b *testing.B) {
 	})
 	_ = foo
 
+	b.Run("Write", func(b *testing.B) {
+		bw := bufio.NewWriter(io.Discard)
+		for i := 0; i < b.N; i++ {
+			for _, c := range tests {
+				_, _ = c.tf.Write(bw, when)
+			}
+		}
+	})
+
 	b.Run("stdlib", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
 			for _, c := range tests {
@@ -525,3 +1023,206 @@ func BenchmarkCompatibility(b *testing.B) {
 	})
 	_ = foo
 }
+
+func TestAppendAllocations(t *testing.T) {
+	when := time.Date(2006, time.January, 2, 3, 4, 5, 12345678, time.UTC)
+	buf := make([]byte, 0, 128)
+
+	for layout, format := range formatMap {
+		for _, create := range []func() (*Formatter, error){
+			func() (*Formatter, error) { return New(format) },
+			func() (*Formatter, error) { return NewCompat(layout) },
+		} {
+			tf, err := create()
+			ensureError(t, err, nil)
+
+			allocs := testing.AllocsPerRun(100, func() {
+				buf = tf.Append(buf[:0], when)
+			})
+			if allocs != 0 {
+				t.Errorf("%q: GOT: %v allocations; WANT: 0", format, allocs)
+			}
+		}
+	}
+}
+
+func BenchmarkFormat(b *testing.B) {
+	buf := make([]byte, 0, 128)
+
+	// Use the same date-time stamp that Go standard library uses,
+	// namely 2006-01-02T15:04:05Z07:00
+	when := time.Date(2006, time.January, 2, 3, 4, 5, 12345678, time.UTC)
+
+	tests := []struct {
+		layout string
+		tf     *Formatter
+	}{
+		{time.ANSIC, nil},
+		{time.UnixDate, nil},
+		{time.RubyDate, nil},
+		{time.RFC822, nil},
+		{time.RFC822Z, nil},
+		{time.RFC850, nil},
+		{time.RFC1123, nil},
+		{time.RFC1123Z, nil},
+		{time.RFC3339, nil},
+		{time.RFC3339Nano, nil},
+		{time.Kitchen, nil},
+		{time.Stamp, nil},
+		{time.StampMilli, nil},
+		{time.StampMicro, nil},
+		{time.StampNano, nil},
+	}
+
+	// Compile the format strings equivalent to each layout, which
+	// BenchmarkCompatibility compiles from the layouts themselves.
+	for i, c := range tests {
+		var err error
+		tests[i].tf, err = New(formatMap[c.layout])
+		ensureError(b, err, nil)
+	}
+
+	b.ResetTimer()
+
+	b.Run("Append", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, c := range tests {
+				buf = c.tf.Append(buf[:0], when)
+			}
+		}
+	})
+
+	b.Run("stdlib", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			for _, c := range tests {
+				buf = when.AppendFormat(buf[:0], c.layout)
+			}
+		}
+	})
+	_ = buf
+}
+
+func TestProgramOpcodes(t *testing.T) {
+	// Each opcode must emit the same result as the function that
+	// formats its verb.
+	functions := map[rune]func([]byte, time.Time) []byte{
+		'a': appendWeekdayShort, 'A': appendWeekdayLong, 'b': appendMonthShort,
+		'B': appendMonthLong, 'C': appendCC, 'd': appendD, 'e': appendE,
+		'h': appendMonthShort, 'H': appendHC, 'I': appendIC, 'j': appendJ,
+		'k': appendK, 'l': appendL, 'm': appendM, 'M': appendMC, 'N': appendNC,
+		'p': appendP, 'P': appendPC, 'q': appendQ, 's': appendS, 'S': appendSC,
+		'u': appendU, 'U': appendUC, 'w': appendW, 'W': appendWC, 'y': appendY,
+		'Y': appendYC, 'z': appendZ, 'Z': appendZC,
+	}
+
+	loc := time.FixedZone("XST", -(3*60*60 + 30*60))
+	when := time.Date(2020, time.January, 1, 0, 0, 0, 0, loc)
+
+	for verb, op := range verbOpcodes {
+		f, ok := functions[verb]
+		if !ok {
+			t.Fatalf("%q: missing function", verb)
+		}
+		native := newFormatter([]instruction{{op: op}})
+		call := newFormatter([]instruction{{op: opCall, call: f}})
+
+		// Cover every day of a leap year and of a common year, at
+		// varying times of day.
+		for i := 0; i < 731; i++ {
+			t1 := when.Add(time.Duration(i) * (24*time.Hour + 37*time.Minute + 11*time.Second + 123456789))
+			if got, want := native.Format(t1), call.Format(t1); got != want {
+				t.Fatalf("%q: %v: GOT: %q; WANT: %q", verb, t1, got, want)
+			}
+		}
+	}
+}
+
+func TestAppendUnix(t *testing.T) {
+	formats := []string{
+		"%c %j %U %W %u %s",
+		"%FT%T.%N%K",
+		"%a, %d %b %Y %T %Z",
+		"%-I:%M%p %P %e %k %l %C %y",
+		"%:z %::z %:::z %5d",
+	}
+	for _, format := range formatMap {
+		formats = append(formats, format)
+	}
+
+	instants := []int64{
+		0,
+		-1,
+		951782400,            // 2000-02-29
+		1609459199,           // 2020-12-31T23:59:59Z
+		-2208988800,          // 1900-01-01
+		-62135596800,         // 0001-01-01
+		253402300799,         // 9999-12-31T23:59:59Z
+		1632959999,           // 2021-09-29T23:59:59Z
+		4107542400 + 86399*3, // 2100-03-03
+	}
+
+	for _, format := range formats {
+		tf, err := New(format)
+		ensureError(t, err, nil)
+
+		for _, offset := range []int{0, 3600, 5*60*60 + 45*60, -(9*60*60 + 30*60), -(4*60*60 + 56*60 + 2)} {
+			loc := fixedZone(offset)
+
+			for _, sec := range instants {
+				for _, nsec := range []int64{0, 5, 999999999} {
+					when := time.Unix(sec, nsec).In(loc)
+					want := string(tf.Append(nil, when))
+
+					if nsec == 0 {
+						if got := string(tf.AppendUnix(nil, sec, offset)); got != want {
+							t.Errorf("%q: AppendUnix(%d, %d): GOT: %q; WANT: %q", format, sec, offset, got, want)
+						}
+					}
+					if sec > -9e9 && sec < 9e9 {
+						if got := string(tf.AppendNanos(nil, sec*1e9+nsec, offset)); got != want {
+							t.Errorf("%q: AppendNanos(%d, %d): GOT: %q; WANT: %q", format, sec*1e9+nsec, offset, got, want)
+						}
+					}
+				}
+			}
+		}
+	}
+
+	t.Run("allocations", func(t *testing.T) {
+		tf, err := New("%FT%T.%3N%:z")
+		ensureError(t, err, nil)
+		buf := make([]byte, 0, 64)
+		allocs := testing.AllocsPerRun(100, func() {
+			buf = tf.AppendNanos(buf[:0], 1632959999123456789, 3600)
+		})
+		if allocs != 0 {
+			t.Errorf("GOT: %v allocations; WANT: 0", allocs)
+		}
+	})
+}
+
+func BenchmarkAppendNanos(b *testing.B) {
+	tf, err := New("%F %T.%N %z")
+	ensureError(b, err, nil)
+
+	buf := make([]byte, 0, 64)
+	nsec := time.Date(2006, time.January, 2, 3, 4, 5, 12345678, time.UTC).UnixNano()
+	loc := time.FixedZone("", 3600)
+
+	b.Run("Append", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf = tf.Append(buf[:0], time.Unix(0, nsec+int64(i)).In(loc))
+		}
+	})
+
+	b.Run("AppendNanos", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			buf = tf.AppendNanos(buf[:0], nsec+int64(i), 3600)
+		}
+	})
+	_ = buf
+}
@@ -0,0 +1,334 @@
+package gosft
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ISODuration is an ISO 8601 duration, such as P3DT4H12M. Unlike a
+// time.Duration, it may include years, months, weeks and days, whose
+// lengths depend on the time to which it is added. Each field is
+// non-negative, and Negative selects the direction of the duration.
+type ISODuration struct {
+	Negative                   bool
+	Years, Months, Weeks, Days int
+	Hours, Minutes, Seconds    int
+	Nanoseconds                int // fraction of a second (range 0 to 999999999)
+}
+
+// ISODurationOf returns the ISO 8601 duration equivalent to d, using
+// hours, minutes and seconds, but not days, because a day is not always
+// 24 hours long. For example, 76 hours and 12 minutes is PT76H12M.
+func ISODurationOf(d time.Duration) ISODuration {
+	magnitude := uint64(d)
+	if d < 0 {
+		magnitude = -magnitude
+	}
+	seconds := magnitude / 1e9
+	return ISODuration{
+		Negative:    d < 0,
+		Hours:       int(seconds / (60 * 60)),
+		Minutes:     int(seconds / 60 % 60),
+		Seconds:     int(seconds % 60),
+		Nanoseconds: int(magnitude % 1e9),
+	}
+}
+
+// ParseISODuration parses an ISO 8601 duration, such as P3DT4H12M,
+// P2W or PT0.5S, optionally preceded by a '-' when it is negative. Only
+// the seconds may have a fraction, which is separated from them by
+// either a '.' or a ','.
+func ParseISODuration(value string) (ISODuration, error) {
+	ps := &parseState{value: value}
+	d, err := ps.isoDuration()
+	if err != nil {
+		return ISODuration{}, err
+	}
+	if ps.i < len(value) {
+		return ISODuration{}, fmt.Errorf("cannot parse %q at index %d: extra text", value, ps.i)
+	}
+	return d, nil
+}
+
+// isoDuration parses an ISO 8601 duration.
+func (ps *parseState) isoDuration() (ISODuration, error) {
+	var d ISODuration
+	start := ps.i
+	if ps.i < len(ps.value) && ps.value[ps.i] == '-' {
+		d.Negative = true
+		ps.i++
+	}
+	if ps.i == len(ps.value) || ps.value[ps.i] != 'P' {
+		ps.i = start
+		return ISODuration{}, ps.errorf("expected duration beginning with 'P'")
+	}
+	ps.i++
+
+	// Each designator follows its number, and they are in the order
+	// of their units, from the largest to the smallest.
+	designators := "YMWD"
+	fields := []*int{&d.Years, &d.Months, &d.Weeks, &d.Days}
+	var components int
+	var inTime bool
+
+	for {
+		if !inTime && ps.i < len(ps.value) && ps.value[ps.i] == 'T' {
+			ps.i++
+			designators = "HMS"
+			fields = []*int{&d.Hours, &d.Minutes, &d.Seconds}
+			components = 0
+			inTime = true
+		}
+		if ps.i == len(ps.value) || ps.value[ps.i] < '0' || ps.value[ps.i] > '9' {
+			break
+		}
+		n, err := ps.number(1, 9, 0, 999999999, false)
+		if err != nil {
+			return ISODuration{}, err
+		}
+		if inTime && ps.i < len(ps.value) && (ps.value[ps.i] == '.' || ps.value[ps.i] == ',') {
+			ps.i++
+			if d.Nanoseconds, err = ps.fraction(1, 9); err != nil {
+				return ISODuration{}, err
+			}
+			designators, fields = "S", fields[len(fields)-1:]
+		}
+		var k int
+		if ps.i < len(ps.value) {
+			k = strings.IndexByte(designators, ps.value[ps.i])
+		}
+		if ps.i == len(ps.value) || k < 0 {
+			if designators == "" {
+				return ISODuration{}, ps.errorf("expected no component following the smallest unit")
+			}
+			return ISODuration{}, ps.errorf("expected one of the designators %q", designators)
+		}
+		*fields[k] = n
+		designators, fields = designators[k+1:], fields[k+1:]
+		components++
+		ps.i++
+	}
+
+	if components == 0 {
+		if inTime {
+			return ISODuration{}, ps.errorf("expected hours, minutes or seconds")
+		}
+		return ISODuration{}, ps.errorf("expected years, months, weeks, days or time")
+	}
+	return d, nil
+}
+
+// Append appends the ISO 8601 representation of d to buf. A duration
+// whose fields are all zero is formatted as PT0S.
+func (d ISODuration) Append(buf []byte) []byte {
+	if d.Negative {
+		buf = append(buf, '-')
+	}
+	buf = append(buf, 'P')
+	for _, c := range []struct {
+		n          int
+		designator byte
+	}{{d.Years, 'Y'}, {d.Months, 'M'}, {d.Weeks, 'W'}, {d.Days, 'D'}} {
+		if c.n != 0 {
+			buf = strconv.AppendInt(buf, int64(c.n), 10)
+			buf = append(buf, c.designator)
+		}
+	}
+	if d.Hours == 0 && d.Minutes == 0 && d.Seconds == 0 && d.Nanoseconds == 0 {
+		if buf[len(buf)-1] == 'P' {
+			buf = append(buf, "T0S"...)
+		}
+		return buf
+	}
+	buf = append(buf, 'T')
+	if d.Hours != 0 {
+		buf = strconv.AppendInt(buf, int64(d.Hours), 10)
+		buf = append(buf, 'H')
+	}
+	if d.Minutes != 0 {
+		buf = strconv.AppendInt(buf, int64(d.Minutes), 10)
+		buf = append(buf, 'M')
+	}
+	if d.Seconds != 0 || d.Nanoseconds != 0 {
+		buf = strconv.AppendInt(buf, int64(d.Seconds), 10)
+		if d.Nanoseconds != 0 {
+			buf = append(buf, '.')
+			buf = appendDurationFraction(buf, d.Nanoseconds, 9, true)
+		}
+		buf = append(buf, 'S')
+	}
+	return buf
+}
+
+// String returns the ISO 8601 representation of d.
+func (d ISODuration) String() string {
+	return string(d.Append(nil))
+}
+
+// Duration returns the time.Duration equivalent to d, treating a week
+// as 7 days and a day as 24 hours. It returns an error when d has years
+// or months, whose lengths vary, or when the result would overflow.
+func (d ISODuration) Duration() (time.Duration, error) {
+	if d.Years != 0 || d.Months != 0 {
+		return 0, fmt.Errorf("cannot convert duration %s having years or months", d)
+	}
+	seconds := uint64(d.Weeks*7+d.Days)*secondsPerDay + uint64(d.Hours)*60*60 + uint64(d.Minutes)*60 + uint64(d.Seconds)
+	limit := uint64(math.MaxInt64)
+	if d.Negative {
+		limit++ // the magnitude of math.MinInt64
+	}
+	if seconds > limit/1e9 || seconds*1e9 > limit-uint64(d.Nanoseconds) {
+		return 0, fmt.Errorf("cannot convert duration %s without overflow", d)
+	}
+	magnitude := seconds*1e9 + uint64(d.Nanoseconds)
+	if d.Negative {
+		return time.Duration(-magnitude), nil
+	}
+	return time.Duration(magnitude), nil
+}
+
+// AddTo returns t plus d. The years, months, weeks and days of d are
+// added using t.AddDate, so that P1D is the same time on the following
+// day even across a daylight saving time transition, and the remainder
+// of d is added as elapsed time.
+func (d ISODuration) AddTo(t time.Time) time.Time {
+	sign := 1
+	if d.Negative {
+		sign = -1
+	}
+	t = t.AddDate(sign*d.Years, sign*d.Months, sign*(d.Weeks*7+d.Days))
+	seconds := int64(d.Hours)*60*60 + int64(d.Minutes)*60 + int64(d.Seconds)
+	return time.Unix(t.Unix()+int64(sign)*seconds, int64(t.Nanosecond()+sign*d.Nanoseconds)).In(t.Location())
+}
+
+// Interval is an ISO 8601 time interval, given by its start and end, by
+// its start and duration, or by its duration and end, which may repeat.
+// The zero Start or End time is absent, and the Duration is used only
+// when either is absent.
+type Interval struct {
+	Start, End time.Time
+	Duration   ISODuration
+
+	// Repeating is true for a repeating interval, such as R5/..., which
+	// has the number of Repetitions, or -1 when it is unbounded.
+	Repeating   bool
+	Repetitions int
+}
+
+// Bounds returns the start and end of iv, computing whichever is absent
+// from the other and the duration of iv.
+func (iv Interval) Bounds() (time.Time, time.Time) {
+	switch {
+	case iv.Start.IsZero():
+		d := iv.Duration
+		d.Negative = !d.Negative
+		return d.AddTo(iv.End), iv.End
+	case iv.End.IsZero():
+		return iv.Start, iv.Duration.AddTo(iv.Start)
+	}
+	return iv.Start, iv.End
+}
+
+// AppendInterval appends the ISO 8601 representation of iv to buf,
+// formatting each of its times in accordance with the format
+// specification of tf, such as "%FT%R%K", and its duration as an ISO
+// 8601 duration.
+func (tf *Formatter) AppendInterval(buf []byte, iv Interval) []byte {
+	if iv.Repeating {
+		buf = append(buf, 'R')
+		if iv.Repetitions >= 0 {
+			buf = strconv.AppendInt(buf, int64(iv.Repetitions), 10)
+		}
+		buf = append(buf, '/')
+	}
+	if iv.Start.IsZero() {
+		buf = iv.Duration.Append(buf)
+	} else {
+		buf = tf.Append(buf, iv.Start)
+	}
+	buf = append(buf, '/')
+	if iv.Start.IsZero() || !iv.End.IsZero() {
+		return tf.Append(buf, iv.End)
+	}
+	return iv.Duration.Append(buf)
+}
+
+// FormatInterval returns the ISO 8601 representation of iv, formatting
+// each of its times in accordance with the format specification of tf.
+func (tf *Formatter) FormatInterval(iv Interval) string {
+	return string(tf.AppendInterval(nil, iv))
+}
+
+// ParseInterval parses an ISO 8601 interval, parsing each of its times
+// in accordance with the format specification of p, as Parse does, and
+// its duration as an ISO 8601 duration. When the format specification
+// includes a '/', each '/' in value is tried in turn as the separator.
+func (p *Parser) ParseInterval(value string) (Interval, error) {
+	var iv Interval
+	rest := value
+	if strings.HasPrefix(rest, "R") {
+		slash := strings.IndexByte(rest, '/')
+		if slash < 0 {
+			return Interval{}, fmt.Errorf("cannot parse %q at index %d: expected '/' following repetitions", value, len(value))
+		}
+		iv.Repeating, iv.Repetitions = true, -1
+		if slash > 1 {
+			ps := &parseState{value: value, i: 1}
+			n, err := ps.number(1, 9, 0, 999999999, false)
+			if err != nil {
+				return Interval{}, err
+			}
+			if ps.i != slash {
+				return Interval{}, ps.errorf("expected '/' following repetitions")
+			}
+			iv.Repetitions = n
+		}
+		rest = rest[slash+1:]
+	}
+
+	err := fmt.Errorf("cannot parse %q at index %d: expected '/' separating interval", value, len(value))
+	for i := 0; i < len(rest); i++ {
+		if rest[i] == '/' {
+			var parsed Interval
+			if parsed, err = p.parseInterval(iv, rest[:i], rest[i+1:]); err == nil {
+				return parsed, nil
+			}
+		}
+	}
+	return Interval{}, err
+}
+
+// parseInterval parses the two parts of an interval, either of which
+// may be a duration, which begins with 'P'.
+func (p *Parser) parseInterval(iv Interval, first, second string) (Interval, error) {
+	var err error
+	switch {
+	case strings.HasPrefix(first, "P") && strings.HasPrefix(second, "P"):
+		return Interval{}, fmt.Errorf("cannot parse %q: interval has two durations", first+"/"+second)
+	case strings.HasPrefix(first, "P"):
+		if iv.Duration, err = ParseISODuration(first); err != nil {
+			return Interval{}, err
+		}
+		if iv.End, err = p.Parse(second); err != nil {
+			return Interval{}, err
+		}
+	case strings.HasPrefix(second, "P"):
+		if iv.Start, err = p.Parse(first); err != nil {
+			return Interval{}, err
+		}
+		if iv.Duration, err = ParseISODuration(second); err != nil {
+			return Interval{}, err
+		}
+	default:
+		if iv.Start, err = p.Parse(first); err != nil {
+			return Interval{}, err
+		}
+		if iv.End, err = p.Parse(second); err != nil {
+			return Interval{}, err
+		}
+	}
+	return iv, nil
+}
@@ -0,0 +1,191 @@
+package gosft
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestISODuration(t *testing.T) {
+	tests := []struct {
+		value string
+		d     ISODuration
+		want  string // formatted result, when it differs from value
+	}{
+		{value: "P3DT4H12M", d: ISODuration{Days: 3, Hours: 4, Minutes: 12}},
+		{value: "P1Y2M3W4DT5H6M7S", d: ISODuration{Years: 1, Months: 2, Weeks: 3, Days: 4, Hours: 5, Minutes: 6, Seconds: 7}},
+		{value: "P2W", d: ISODuration{Weeks: 2}},
+		{value: "PT0.5S", d: ISODuration{Nanoseconds: 500000000}},
+		{value: "PT1,000000001S", d: ISODuration{Seconds: 1, Nanoseconds: 1}, want: "PT1.000000001S"},
+		{value: "-P1DT1S", d: ISODuration{Negative: true, Days: 1, Seconds: 1}},
+		{value: "P0D", d: ISODuration{}, want: "PT0S"},
+		{value: "PT0S", d: ISODuration{}},
+		{value: "PT36H", d: ISODuration{Hours: 36}},
+		{value: "P1M", d: ISODuration{Months: 1}},
+		{value: "PT1M", d: ISODuration{Minutes: 1}},
+	}
+
+	for _, c := range tests {
+		t.Run(c.value, func(t *testing.T) {
+			got, err := ParseISODuration(c.value)
+			ensureError(t, err, nil)
+			if got != c.d {
+				t.Errorf("GOT: %+v; WANT: %+v", got, c.d)
+			}
+			want := c.want
+			if want == "" {
+				want = c.value
+			}
+			if got := c.d.String(); got != want {
+				t.Errorf("GOT: %q; WANT: %q", got, want)
+			}
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			value, want string
+		}{
+			{"", `cannot parse "" at index 0: expected duration beginning with 'P'`},
+			{"3D", `cannot parse "3D" at index 0: expected duration beginning with 'P'`},
+			{"P", `cannot parse "P" at index 1: expected years, months, weeks, days or time`},
+			{"P1DT", `cannot parse "P1DT" at index 4: expected hours, minutes or seconds`},
+			{"P1D2Y", `cannot parse "P1D2Y" at index 4: expected no component following the smallest unit`},
+			{"PT1H2D", `cannot parse "PT1H2D" at index 5: expected one of the designators "MS"`},
+			{"PT1.5M", `cannot parse "PT1.5M" at index 5: expected one of the designators "S"`},
+			{"P1.5D", `cannot parse "P1.5D" at index 2: expected one of the designators "YMWD"`},
+			{"P1", `cannot parse "P1" at index 2: expected one of the designators "YMWD"`},
+			{"P1DX", `cannot parse "P1DX" at index 3: extra text`},
+		}
+
+		for _, c := range tests {
+			_, err := ParseISODuration(c.value)
+			ensureError(t, err, errors.New(c.want))
+		}
+	})
+
+	t.Run("conversions", func(t *testing.T) {
+		for _, d := range []time.Duration{0, 76*time.Hour + 12*time.Minute, -1500 * time.Millisecond, math.MaxInt64, math.MinInt64} {
+			got, err := ISODurationOf(d).Duration()
+			ensureError(t, err, nil)
+			if got != d {
+				t.Errorf("GOT: %v; WANT: %v", got, d)
+			}
+		}
+
+		if got, want := ISODurationOf(76*time.Hour+12*time.Minute).String(), "PT76H12M"; got != want {
+			t.Errorf("GOT: %q; WANT: %q", got, want)
+		}
+
+		d, err := ISODuration{Weeks: 1, Days: 1}.Duration()
+		ensureError(t, err, nil)
+		if got, want := d, 8*24*time.Hour; got != want {
+			t.Errorf("GOT: %v; WANT: %v", got, want)
+		}
+
+		_, err = ISODuration{Months: 1}.Duration()
+		ensureError(t, err, errors.New("cannot convert duration P1M having years or months"))
+		_, err = ISODuration{Weeks: 20000}.Duration()
+		ensureError(t, err, errors.New("cannot convert duration P20000W without overflow"))
+	})
+
+	t.Run("add", func(t *testing.T) {
+		newYork, err := time.LoadLocation("America/New_York")
+		if err != nil {
+			t.Skip(err)
+		}
+		// Daylight saving time begins on March 8, 2026.
+		start := time.Date(2026, time.March, 7, 12, 0, 0, 0, newYork)
+
+		tests := []struct {
+			d    string
+			want time.Time
+		}{
+			{"P1D", time.Date(2026, time.March, 8, 12, 0, 0, 0, newYork)},
+			{"PT24H", time.Date(2026, time.March, 8, 13, 0, 0, 0, newYork)},
+			{"P1M1DT0.5S", time.Date(2026, time.April, 8, 12, 0, 0, 500000000, newYork)},
+			{"-P1W", time.Date(2026, time.February, 28, 12, 0, 0, 0, newYork)},
+			{"-PT0.5S", time.Date(2026, time.March, 7, 11, 59, 59, 500000000, newYork)},
+		}
+
+		for _, c := range tests {
+			d, err := ParseISODuration(c.d)
+			ensureError(t, err, nil)
+			if got := d.AddTo(start); !got.Equal(c.want) || got.Location() != newYork {
+				t.Errorf("%s: GOT: %v; WANT: %v", c.d, got, c.want)
+			}
+		}
+	})
+}
+
+func TestInterval(t *testing.T) {
+	tf, err := New("%FT%R%K")
+	ensureError(t, err, nil)
+	p, err := NewParser("%FT%R%K")
+	ensureError(t, err, nil)
+
+	start := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2026, time.February, 1, 0, 0, 0, 0, time.UTC)
+	month := ISODuration{Months: 1}
+
+	tests := []struct {
+		value string
+		iv    Interval
+	}{
+		{"2026-01-01T00:00Z/2026-02-01T00:00Z", Interval{Start: start, End: end}},
+		{"2026-01-01T00:00Z/P1M", Interval{Start: start, Duration: month}},
+		{"P1M/2026-02-01T00:00Z", Interval{End: end, Duration: month}},
+		{"R5/2026-01-01T00:00Z/P1M", Interval{Start: start, Duration: month, Repeating: true, Repetitions: 5}},
+		{"R/P1M/2026-02-01T00:00Z", Interval{End: end, Duration: month, Repeating: true, Repetitions: -1}},
+		{"R0/2026-01-01T00:00Z/2026-02-01T00:00Z", Interval{Start: start, End: end, Repeating: true}},
+	}
+
+	for _, c := range tests {
+		t.Run(c.value, func(t *testing.T) {
+			if got, want := tf.FormatInterval(c.iv), c.value; got != want {
+				t.Errorf("GOT: %q; WANT: %q", got, want)
+			}
+
+			got, err := p.ParseInterval(c.value)
+			ensureError(t, err, nil)
+			if got != c.iv {
+				t.Errorf("GOT: %+v; WANT: %+v", got, c.iv)
+			}
+
+			gotStart, gotEnd := got.Bounds()
+			if !gotStart.Equal(start) || !gotEnd.Equal(end) {
+				t.Errorf("GOT: %v/%v; WANT: %v/%v", gotStart, gotEnd, start, end)
+			}
+		})
+	}
+
+	t.Run("slashes", func(t *testing.T) {
+		p, err := NewParser("%D")
+		ensureError(t, err, nil)
+
+		got, err := p.ParseInterval("01/01/26/02/01/26")
+		ensureError(t, err, nil)
+		if want := (Interval{Start: start, End: end}); got != want {
+			t.Errorf("GOT: %+v; WANT: %+v", got, want)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			value, want string
+		}{
+			{"2026-01-01T00:00Z", `cannot parse "2026-01-01T00:00Z" at index 17: expected '/' separating interval`},
+			{"R5", `cannot parse "R5" at index 2: expected '/' following repetitions`},
+			{"Rx/P1M/2026-02-01T00:00Z", `cannot parse "Rx/P1M/2026-02-01T00:00Z" at index 1: expected 1 to 9 digits`},
+			{"P1M/P1D", `cannot parse "P1M/P1D": interval has two durations`},
+			{"P1X/2026-02-01T00:00Z", `cannot parse "P1X" at index 2: expected one of the designators "YMWD"`},
+			{"2026-01-01T00:00Z/2026-02-01", `cannot parse "2026-02-01" at index 10: expected "T"`},
+		}
+
+		for _, c := range tests {
+			_, err := p.ParseInterval(c.value)
+			ensureError(t, err, errors.New(c.want))
+		}
+	})
+}
@@ -0,0 +1,273 @@
+package gosft
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// elementVerbs maps each Go reference layout element that has an
+// equivalent format verb to that verb. The MST element differs from %Z
+// only for time zones without an abbreviation, for which the time
+// package emits the numeric offset while %Z emits nothing.
+var elementVerbs = map[string]string{
+	"January":   "%B",
+	"Jan":       "%b",
+	"1":         "%-m",
+	"01":        "%m",
+	"Monday":    "%A",
+	"Mon":       "%a",
+	"2":         "%-d",
+	"_2":        "%e",
+	"02":        "%d",
+	"__2":       "%_j",
+	"002":       "%j",
+	"15":        "%H",
+	"3":         "%-I",
+	"03":        "%I",
+	"4":         "%-M",
+	"04":        "%M",
+	"5":         "%-S",
+	"05":        "%S",
+	"2006":      "%Y",
+	"06":        "%y",
+	"PM":        "%p",
+	"pm":        "%P",
+	"MST":       "%Z",
+	"-0700":     "%z",
+	"-07:00":    "%:z",
+	"-07:00:00": "%::z",
+	"Z07:00":    "%K",
+}
+
+// verbElements is the inverse of elementVerbs.
+var verbElements = func() map[string]string {
+	m := make(map[string]string, len(elementVerbs))
+	for element, verb := range elementVerbs {
+		m[verb] = element
+	}
+	return m
+}()
+
+// verbAliases maps format verbs that emit the same text as one of the
+// verbs in elementVerbs to that verb.
+var verbAliases = map[string]string{
+	"%h":  "%b",
+	"%-l": "%-I",
+	"%-e": "%-d",
+	"%_d": "%e",
+	"%0e": "%d",
+}
+
+// ToGoLayout returns the Go reference layout equivalent to the provided
+// format string, for use with the time package. It returns an error
+// naming the first format verb that has no equivalent layout element,
+// such as %s or %u, or when literal text of the format would be
+// mistaken for a layout element, such as the "1" in "%H:%M version 1".
+// Composite verbs, such as %c and %F, are translated using the POSIX
+// locale.
+func ToGoLayout(format string) (string, error) {
+	lb := layoutBuilder{elements: make(map[int]int)}
+	if err := lb.translate(format, -1); err != nil {
+		return "", err
+	}
+
+	// The time package has no means of escaping literal text, so ensure
+	// the layout is read back as the elements it was built from.
+	layout := string(lb.layout)
+	for i := 0; i < len(layout); {
+		element, _ := nextLayoutElement(layout, i)
+		n, ok := lb.elements[i]
+		switch {
+		case ok && n == len(element):
+			i += n
+		case !ok && element == "":
+			i++
+		case !ok:
+			return "", fmt.Errorf("cannot translate text at index %d into a Go layout: %q would be read as a layout element", lb.origins[i], element)
+		default:
+			// The text following the element changes how it is read.
+			next := i + n
+			if next == len(layout) {
+				next = i
+			}
+			return "", fmt.Errorf("cannot translate text at index %d into a Go layout: it would be read as part of the preceding layout element", lb.origins[next])
+		}
+	}
+
+	return layout, nil
+}
+
+// layoutBuilder accumulates a Go reference layout, recording where each
+// layout element begins and which part of the format string produced
+// each byte of the layout.
+type layoutBuilder struct {
+	layout   []byte
+	origins  []int       // index within the format of each byte of layout
+	elements map[int]int // length of each layout element keyed by its index
+}
+
+// translate appends the layout equivalent to format. When origin is not
+// negative, it is reported as the index of all text within format,
+// which is used for the expansions of composite verbs.
+func (lb *layoutBuilder) translate(format string, origin int) error {
+	at := func(i int) int {
+		if origin >= 0 {
+			return origin
+		}
+		return i
+	}
+
+	var foundPercent bool
+	var start int // index of the percent sign of the current verb
+	var modifier rune
+	var flags []byte
+	var width int
+
+	for ri, rune := range format {
+		if !foundPercent {
+			if rune == '%' {
+				foundPercent = true
+				start = ri
+			} else {
+				lb.literal(string(rune), at(ri))
+			}
+			continue
+		}
+		if modifier == 0 {
+			if width == 0 && strings.ContainsRune(formatFlags, rune) {
+				flags = append(flags, byte(rune))
+				continue
+			}
+			if rune >= '0' && rune <= '9' {
+				width = width*10 + int(rune-'0')
+				continue
+			}
+			if rune == 'E' || rune == 'O' {
+				modifier = rune
+				continue
+			}
+		}
+		if rune == ':' {
+			// Colons remain part of the verb, as in %:z.
+			continue
+		}
+
+		verb := format[start : ri+1]
+		var err error
+
+		switch {
+		case rune == 'N' && len(flags) == 0 && modifier == 0:
+			err = lb.fraction(verb, width, at(start))
+		case width > 0 || modifier != 0:
+			// Alternative representations and field widths have no
+			// equivalent layout elements.
+			err = fmt.Errorf("cannot translate format verb %q at index %d into a Go layout", verb, at(start))
+		case verb == "%%":
+			lb.literal("%", at(start))
+		case verb == "%n":
+			lb.literal("\n", at(start))
+		case verb == "%t":
+			lb.literal("\t", at(start))
+		case len(flags) == 0 && compositeExpansions[rune] != "":
+			err = lb.translate(compositeExpansions[rune], at(start))
+		default:
+			canonical := verb
+			if alias, ok := verbAliases[verb]; ok {
+				canonical = alias
+			}
+			element, ok := verbElements[canonical]
+			if !ok || (rune == 'j' && !yearDayLayouts) {
+				err = fmt.Errorf("cannot translate format verb %q at index %d into a Go layout", verb, at(start))
+				break
+			}
+			lb.element(element, at(start))
+		}
+		if err != nil {
+			return err
+		}
+
+		foundPercent = false
+		modifier = 0
+		flags = nil
+		width = 0
+	}
+
+	if foundPercent {
+		return &TrailingPercentError{Format: format, Offset: start}
+	}
+	return nil
+}
+
+func (lb *layoutBuilder) literal(s string, origin int) {
+	for i := 0; i < len(s); i++ {
+		lb.origins = append(lb.origins, origin)
+	}
+	lb.layout = append(lb.layout, s...)
+}
+
+func (lb *layoutBuilder) element(s string, origin int) {
+	lb.elements[len(lb.layout)] = len(s)
+	lb.literal(s, origin)
+}
+
+// fraction appends the layout element for fractional seconds having the
+// provided number of digits, or returns an error naming verb. The time
+// package requires fractional seconds to follow a period or comma, which
+// becomes part of the element.
+func (lb *layoutBuilder) fraction(verb string, digits, origin int) error {
+	if digits == 0 {
+		digits = 9
+	}
+	n := len(lb.layout)
+	if digits > 9 || n == 0 || (lb.layout[n-1] != '.' && lb.layout[n-1] != ',') {
+		return fmt.Errorf("cannot translate format verb %q at index %d into a Go layout: fractional seconds must have at most 9 digits and follow a period or comma", verb, origin)
+	}
+	separator := lb.layout[n-1]
+	lb.layout, lb.origins = lb.layout[:n-1], lb.origins[:n-1]
+	lb.element(string(separator)+strings.Repeat("0", digits), origin)
+	return nil
+}
+
+// FromGoLayout returns the format string equivalent to the provided Go
+// reference layout. It returns an error naming the first layout element
+// that has no equivalent format verb.
+func FromGoLayout(layout string) (string, error) {
+	var format []byte
+
+	for i := 0; i < len(layout); {
+		element, _ := nextLayoutElement(layout, i)
+		if element == "" {
+			if layout[i] == '%' {
+				format = append(format, '%')
+			}
+			format = append(format, layout[i])
+			i++
+			continue
+		}
+		verb, ok := elementVerbs[element]
+		if !ok {
+			verb, ok = fractionVerb(element)
+		}
+		if !ok {
+			return "", fmt.Errorf("cannot translate layout element %q at index %d into a format string", element, i)
+		}
+		format = append(format, verb...)
+		i += len(element)
+	}
+
+	return string(format), nil
+}
+
+// fractionVerb returns the separator and format verb equivalent to a
+// layout element for fractional seconds, such as ".000", or false when
+// element is not such an element.
+func fractionVerb(element string) (string, bool) {
+	if len(element) < 2 || (element[0] != '.' && element[0] != ',') || element[1] != '0' {
+		return "", false
+	}
+	if digits := len(element) - 1; digits < 9 {
+		return element[:1] + "%" + strconv.Itoa(digits) + "N", true
+	}
+	return element[:1] + "%N", true
+}
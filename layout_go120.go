@@ -0,0 +1,8 @@
+//go:build go1.20
+
+package gosft
+
+// yearDayLayouts is true when the time package recognizes the 002 and
+// __2 layout elements for the day of the year, which were added in Go
+// 1.20.
+const yearDayLayouts = true
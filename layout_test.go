@@ -0,0 +1,128 @@
+package gosft
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestToGoLayout(t *testing.T) {
+	tests := []struct {
+		format, want string
+	}{
+		{"%F %T", "2006-01-02 15:04:05"},
+		{"%c", "Mon Jan _2 15:04:05 2006"},
+		{"%a, %d %b %Y %H:%M:%S %Z", time.RFC1123},
+		{"%d %b %y %H:%M %z", time.RFC822Z},
+		{"%FT%T%K", time.RFC3339},
+		{"%FT%T%:z|%::z", "2006-01-02T15:04:05-07:00|-07:00:00"},
+		{"%-I:%M%p", time.Kitchen},
+		{"%b %e %H:%M:%S.%6N", time.StampMicro},
+		{"%Y-%m-%dT%H:%M:%S,%N", "2006-01-02T15:04:05,000000000"},
+		{"%-m/%-d/%y %-l:%-M:%-S %P", "1/2/06 3:4:5 pm"},
+		{"%e|%_d|%0e|%-e|%h", "_2|_2|02|2|Jan"},
+		{"%j %_j", "002 __2"},
+		{"%A%n%B%t%%", "Monday\nJanuary\t%"},
+		{"week of %D", "week of 01/02/06"},
+		{"", ""},
+	}
+
+	for _, c := range tests {
+		t.Run(c.format, func(t *testing.T) {
+			got, err := ToGoLayout(c.format)
+			ensureError(t, err, nil)
+			if got != c.want {
+				t.Errorf("GOT: %q; WANT: %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestToGoLayoutErrors(t *testing.T) {
+	tests := []struct {
+		format, want string
+	}{
+		{"%s", `cannot translate format verb "%s" at index 0`},
+		{"%F %u", `cannot translate format verb "%u" at index 3`},
+		{"%k", `cannot translate format verb "%k" at index 0`},
+		{"%-y", `cannot translate format verb "%-y" at index 0`},
+		{"%5d", `cannot translate format verb "%5d" at index 0`},
+		{"%Ey", `cannot translate format verb "%Ey" at index 0`},
+		{"%_F", `cannot translate format verb "%_F" at index 0`},
+		{"%N", `cannot translate format verb "%N" at index 0`},
+		{"%S.%12N", `cannot translate format verb "%12N" at index 3`},
+		{"%T version 1", `cannot translate text at index 11 into a Go layout: "1" would be read as a layout element`},
+		{"Monday %F", `cannot translate text at index 0 into a Go layout: "Monday" would be read as a layout element`},
+		{"%-m5", "cannot translate text at index 3 into a Go layout: it would be read as part of the preceding layout element"},
+		{"%bu", "cannot translate text at index 2 into a Go layout: it would be read as part of the preceding layout element"},
+		{"%S.%3N0", "cannot translate text at index 6 into a Go layout: it would be read as part of the preceding layout element"},
+		{"%:::z", `cannot translate format verb "%:::z" at index 0`},
+		{"%F %", "cannot find closing format verb"},
+	}
+
+	for _, c := range tests {
+		t.Run(c.format, func(t *testing.T) {
+			_, err := ToGoLayout(c.format)
+			ensureError(t, err, errors.New(c.want))
+		})
+	}
+}
+
+func TestFromGoLayout(t *testing.T) {
+	tests := []struct {
+		layout, want string
+	}{
+		{time.ANSIC, "%a %b %e %H:%M:%S %Y"},
+		{time.UnixDate, "%a %b %e %H:%M:%S %Z %Y"},
+		{time.RFC822Z, "%d %b %y %H:%M %z"},
+		{time.RFC850, "%A, %d-%b-%y %H:%M:%S %Z"},
+		{time.RFC3339, "%Y-%m-%dT%H:%M:%S%K"},
+		{"15:04 -07:00 -07:00:00", "%H:%M %:z %::z"},
+		{time.Kitchen, "%-I:%M%p"},
+		{time.StampMilli, "%b %e %H:%M:%S.%3N"},
+		{time.StampNano, "%b %e %H:%M:%S.%N"},
+		{"1/2/06 3:4:5 pm __2 002", "%-m/%-d/%y %-I:%-M:%-S %P %_j %j"},
+		{"% of January", "%% of %B"},
+		{"15:04:05,00", "%H:%M:%S,%2N"},
+	}
+
+	times := []time.Time{
+		time.Date(2006, time.January, 2, 15, 4, 5, 123456789, time.UTC),
+		time.Date(2021, time.September, 30, 0, 0, 0, 0, time.UTC),
+		time.Date(2021, time.December, 31, 12, 59, 59, 100000000, time.UTC),
+	}
+
+	for _, c := range tests {
+		t.Run(c.layout, func(t *testing.T) {
+			got, err := FromGoLayout(c.layout)
+			ensureError(t, err, nil)
+			if got != c.want {
+				t.Fatalf("GOT: %q; WANT: %q", got, c.want)
+			}
+
+			// Both must produce the same output.
+			tf, err := New(got)
+			ensureError(t, err, nil)
+			for _, when := range times {
+				if got, want := tf.Format(when), when.Format(c.layout); got != want {
+					t.Errorf("GOT: %q; WANT: %q", got, want)
+				}
+			}
+
+			// And translating back must yield the original layout.
+			layout, err := ToGoLayout(got)
+			ensureError(t, err, nil)
+			if layout != c.layout {
+				t.Errorf("GOT: %q; WANT: %q", layout, c.layout)
+			}
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := FromGoLayout("15:04:05.999")
+		ensureError(t, err, errors.New(`cannot translate layout element ".999" at index 8 into a format string`))
+
+		_, err = FromGoLayout("15:04:05Z0700")
+		ensureError(t, err, errors.New(`cannot translate layout element "Z0700" at index 8 into a format string`))
+	})
+}
@@ -0,0 +1,414 @@
+package gosft
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Locale provides the names and layouts used by the locale dependent
+// format verbs, in the same manner as the LC_TIME category of a POSIX
+// locale.
+type Locale struct {
+	// Days and AbbreviatedDays hold the full and abbreviated weekday
+	// names, starting with Sunday, used by %A and %a (DAY_1–7 and
+	// ABDAY_1–7).
+	Days, AbbreviatedDays [7]string
+
+	// Months and AbbreviatedMonths hold the full and abbreviated month
+	// names, starting with January, used by %B, %b, and %h (MON_1–12
+	// and ABMON_1–12).
+	Months, AbbreviatedMonths [12]string
+
+	// AM and PM are emitted by %p, and in lowercase by %P. Both may be
+	// empty for locales that do not use a 12-hour clock, in which case
+	// the POSIX layouts of %r and %+ omit %p, and %r uses a 24-hour
+	// clock.
+	AM, PM string
+
+	// DateTime, Date, Time and TimeAMPM are the format strings used by
+	// %c, %x, %X and %r respectively (D_T_FMT, D_FMT, T_FMT and
+	// T_FMT_AMPM). When empty, those verbs use the POSIX layout.
+	DateTime, Date, Time, TimeAMPM string
+
+	// Alternatives provides the era table, era formats, and alternative
+	// digits used by the %E and %O modifiers.
+	Alternatives
+
+	// Relative provides the words used by a RelativeFormatter. When
+	// empty, those of the POSIX locale are used.
+	Relative RelativeNames
+}
+
+// NewWithLocale returns a formatter that formats times according to the
+// provided format string, using locale for the names and layouts of the
+// locale dependent format verbs.
+func NewWithLocale(format string, locale Locale) (*Formatter, error) {
+	return create(format, &options{locale: &locale})
+}
+
+// LookupLocale returns the built-in locale having the provided name,
+// such as "de_DE", or false when there is no such locale. The "C" and
+// "POSIX" locales provide the default behavior of New.
+func LookupLocale(name string) (Locale, bool) {
+	locale, ok := locales[name]
+	if !ok {
+		return Locale{}, false
+	}
+	// Prevent callers from modifying the tables of the built-in locale.
+	locale.Eras = append([]Era(nil), locale.Eras...)
+	locale.Digits = append([]string(nil), locale.Digits...)
+	return locale, true
+}
+
+// localeLayouts24 maps each format verb whose POSIX layout includes %p
+// to the layout used instead of its composite expansion when the locale
+// has neither AM nor PM strings.
+var localeLayouts24 = map[rune]string{
+	'r': "%H:%M:%S",
+	'+': "%a %b %e %H:%M:%S %Z %Y",
+}
+
+// makeLocaleFormatter returns the formatter for a locale dependent
+// format verb, or nil when verb does not depend on the locale. It also
+// reports whether the result may change within a second, because a
+// locale layout includes %N.
+func makeLocaleFormatter(verb rune, locale *Locale) (func([]byte, time.Time) []byte, bool, error) {
+	switch verb {
+	case 'a':
+		names := locale.AbbreviatedDays
+		return func(buf []byte, t time.Time) []byte {
+			return append(buf, names[t.Weekday()]...)
+		}, false, nil
+	case 'A':
+		names := locale.Days
+		return func(buf []byte, t time.Time) []byte {
+			return append(buf, names[t.Weekday()]...)
+		}, false, nil
+	case 'b', 'h':
+		names := locale.AbbreviatedMonths
+		return func(buf []byte, t time.Time) []byte {
+			return append(buf, names[t.Month()-1]...)
+		}, false, nil
+	case 'B':
+		names := locale.Months
+		return func(buf []byte, t time.Time) []byte {
+			return append(buf, names[t.Month()-1]...)
+		}, false, nil
+	case 'p', 'P':
+		am, pm := locale.AM, locale.PM
+		if verb == 'P' {
+			am, pm = strings.ToLower(am), strings.ToLower(pm)
+		}
+		return func(buf []byte, t time.Time) []byte {
+			if t.Hour() < 12 {
+				buf = append(buf, am...)
+			} else {
+				buf = append(buf, pm...)
+			}
+			return buf
+		}, false, nil
+	case 'c', 'r', 'x', 'X', '+':
+		layout := map[rune]string{'c': locale.DateTime, 'r': locale.TimeAMPM, 'x': locale.Date, 'X': locale.Time}[verb]
+		if layout == "" {
+			layout = compositeExpansions[verb]
+			if layout24, ok := localeLayouts24[verb]; ok && locale.AM == "" && locale.PM == "" {
+				layout = layout24
+			}
+		}
+		// Compile without the locale layouts to prevent a layout from
+		// referring to itself.
+		nested := *locale
+		nested.DateTime, nested.Date, nested.Time, nested.TimeAMPM = "", "", "", ""
+		tf, err := create(layout, &options{locale: &nested})
+		if err != nil {
+			return nil, false, fmt.Errorf("cannot compile locale format %q: %w", layout, err)
+		}
+		return tf.Append, tf.subsecond(), nil
+	}
+	return nil, false, nil
+}
+
+var posixLocale = Locale{
+	Days:              [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+	AbbreviatedDays:   [7]string{"Sun", "Mon", "Tue", "Wed", "Thu", "Fri", "Sat"},
+	Months:            [12]string{"January", "February", "March", "April", "May", "June", "July", "August", "September", "October", "November", "December"},
+	AbbreviatedMonths: [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+	AM:                "AM",
+	PM:                "PM",
+	Relative: RelativeNames{
+		Now:    "now",
+		Past:   "%s ago",
+		Future: "in %s",
+		Long: [numUnits][2]string{
+			{"%d second", "%d seconds"},
+			{"%d minute", "%d minutes"},
+			{"%d hour", "%d hours"},
+			{"%d day", "%d days"},
+			{"%d week", "%d weeks"},
+			{"%d month", "%d months"},
+			{"%d year", "%d years"},
+		},
+		Short: [numUnits][2]string{
+			{"%d sec", "%d sec"},
+			{"%d min", "%d min"},
+			{"%d hr", "%d hr"},
+			{"%d day", "%d days"},
+			{"%d wk", "%d wk"},
+			{"%d mo", "%d mo"},
+			{"%d yr", "%d yr"},
+		},
+		Narrow: [numUnits][2]string{
+			{"%ds", "%ds"},
+			{"%dm", "%dm"},
+			{"%dh", "%dh"},
+			{"%dd", "%dd"},
+			{"%dw", "%dw"},
+			{"%dmo", "%dmo"},
+			{"%dy", "%dy"},
+		},
+	},
+}
+
+// locales holds the built-in locales, whose values are taken from the
+// GNU C Library locale definitions.
+var locales = map[string]Locale{
+	"C":     posixLocale,
+	"POSIX": posixLocale,
+	"en_US": {
+		Days:              posixLocale.Days,
+		AbbreviatedDays:   posixLocale.AbbreviatedDays,
+		Months:            posixLocale.Months,
+		AbbreviatedMonths: posixLocale.AbbreviatedMonths,
+		AM:                "AM",
+		PM:                "PM",
+		DateTime:          "%a %d %b %Y %r %Z",
+		Date:              "%m/%d/%Y",
+		Time:              "%r",
+		TimeAMPM:          "%I:%M:%S %p",
+		Relative:          posixLocale.Relative,
+	},
+	"en_GB": {
+		Days:              posixLocale.Days,
+		AbbreviatedDays:   posixLocale.AbbreviatedDays,
+		Months:            posixLocale.Months,
+		AbbreviatedMonths: posixLocale.AbbreviatedMonths,
+		AM:                "am",
+		PM:                "pm",
+		DateTime:          "%a %d %b %Y %T %Z",
+		Date:              "%d/%m/%y",
+		Time:              "%T",
+		TimeAMPM:          "%l:%M:%S %P %Z",
+		Relative:          posixLocale.Relative,
+	},
+	"de_DE": {
+		Days:              [7]string{"Sonntag", "Montag", "Dienstag", "Mittwoch", "Donnerstag", "Freitag", "Samstag"},
+		AbbreviatedDays:   [7]string{"So", "Mo", "Di", "Mi", "Do", "Fr", "Sa"},
+		Months:            [12]string{"Januar", "Februar", "März", "April", "Mai", "Juni", "Juli", "August", "September", "Oktober", "November", "Dezember"},
+		AbbreviatedMonths: [12]string{"Jan", "Feb", "Mär", "Apr", "Mai", "Jun", "Jul", "Aug", "Sep", "Okt", "Nov", "Dez"},
+		DateTime:          "%a %d %b %Y %T %Z",
+		Date:              "%d.%m.%Y",
+		Time:              "%T",
+		Relative: RelativeNames{
+			Now:    "jetzt",
+			Past:   "vor %s",
+			Future: "in %s",
+			Long: [numUnits][2]string{
+				{"%d Sekunde", "%d Sekunden"},
+				{"%d Minute", "%d Minuten"},
+				{"%d Stunde", "%d Stunden"},
+				{"%d Tag", "%d Tagen"},
+				{"%d Woche", "%d Wochen"},
+				{"%d Monat", "%d Monaten"},
+				{"%d Jahr", "%d Jahren"},
+			},
+			Short: [numUnits][2]string{
+				{"%d Sek.", "%d Sek."},
+				{"%d Min.", "%d Min."},
+				{"%d Std.", "%d Std."},
+				{"%d Tag", "%d Tagen"},
+				{"%d Wo.", "%d Wo."},
+				{"%d Mon.", "%d Mon."},
+				{"%d J.", "%d J."},
+			},
+			Narrow: [numUnits][2]string{
+				{"%d s", "%d s"},
+				{"%d m", "%d m"},
+				{"%d h", "%d h"},
+				{"%d T.", "%d T."},
+				{"%d W.", "%d W."},
+				{"%d M.", "%d M."},
+				{"%d J.", "%d J."},
+			},
+		},
+	},
+	"fr_FR": {
+		Days:              [7]string{"dimanche", "lundi", "mardi", "mercredi", "jeudi", "vendredi", "samedi"},
+		AbbreviatedDays:   [7]string{"dim.", "lun.", "mar.", "mer.", "jeu.", "ven.", "sam."},
+		Months:            [12]string{"janvier", "février", "mars", "avril", "mai", "juin", "juillet", "août", "septembre", "octobre", "novembre", "décembre"},
+		AbbreviatedMonths: [12]string{"janv.", "févr.", "mars", "avril", "mai", "juin", "juil.", "août", "sept.", "oct.", "nov.", "déc."},
+		DateTime:          "%a %d %b %Y %T %Z",
+		Date:              "%d/%m/%Y",
+		Time:              "%T",
+		Relative: RelativeNames{
+			Now:    "maintenant",
+			Past:   "il y a %s",
+			Future: "dans %s",
+			Long: [numUnits][2]string{
+				{"%d seconde", "%d secondes"},
+				{"%d minute", "%d minutes"},
+				{"%d heure", "%d heures"},
+				{"%d jour", "%d jours"},
+				{"%d semaine", "%d semaines"},
+				{"%d mois", "%d mois"},
+				{"%d an", "%d ans"},
+			},
+			Short: [numUnits][2]string{
+				{"%d s", "%d s"},
+				{"%d min", "%d min"},
+				{"%d h", "%d h"},
+				{"%d j", "%d j"},
+				{"%d sem.", "%d sem."},
+				{"%d m.", "%d m."},
+				{"%d a.", "%d a."},
+			},
+			Narrow: [numUnits][2]string{
+				{"%ds", "%ds"},
+				{"%dmin", "%dmin"},
+				{"%dh", "%dh"},
+				{"%dj", "%dj"},
+				{"%dsem.", "%dsem."},
+				{"%dm.", "%dm."},
+				{"%da.", "%da."},
+			},
+		},
+	},
+	"es_ES": {
+		Days:              [7]string{"domingo", "lunes", "martes", "miércoles", "jueves", "viernes", "sábado"},
+		AbbreviatedDays:   [7]string{"dom", "lun", "mar", "mié", "jue", "vie", "sáb"},
+		Months:            [12]string{"enero", "febrero", "marzo", "abril", "mayo", "junio", "julio", "agosto", "septiembre", "octubre", "noviembre", "diciembre"},
+		AbbreviatedMonths: [12]string{"ene", "feb", "mar", "abr", "may", "jun", "jul", "ago", "sep", "oct", "nov", "dic"},
+		DateTime:          "%a %d %b %Y %T %Z",
+		Date:              "%d/%m/%y",
+		Time:              "%T",
+		Relative: RelativeNames{
+			Now:    "ahora",
+			Past:   "hace %s",
+			Future: "dentro de %s",
+			Long: [numUnits][2]string{
+				{"%d segundo", "%d segundos"},
+				{"%d minuto", "%d minutos"},
+				{"%d hora", "%d horas"},
+				{"%d día", "%d días"},
+				{"%d semana", "%d semanas"},
+				{"%d mes", "%d meses"},
+				{"%d año", "%d años"},
+			},
+			Short: [numUnits][2]string{
+				{"%d s", "%d s"},
+				{"%d min", "%d min"},
+				{"%d h", "%d h"},
+				{"%d d", "%d d"},
+				{"%d sem.", "%d sem."},
+				{"%d m", "%d m"},
+				{"%d a", "%d a"},
+			},
+			Narrow: [numUnits][2]string{
+				{"%ds", "%ds"},
+				{"%dmin", "%dmin"},
+				{"%dh", "%dh"},
+				{"%dd", "%dd"},
+				{"%dsem", "%dsem"},
+				{"%dm", "%dm"},
+				{"%da", "%da"},
+			},
+		},
+	},
+	"ja_JP": {
+		Days:              [7]string{"日曜日", "月曜日", "火曜日", "水曜日", "木曜日", "金曜日", "土曜日"},
+		AbbreviatedDays:   [7]string{"日", "月", "火", "水", "木", "金", "土"},
+		Months:            [12]string{"1月", "2月", "3月", "4月", "5月", "6月", "7月", "8月", "9月", "10月", "11月", "12月"},
+		AbbreviatedMonths: [12]string{" 1月", " 2月", " 3月", " 4月", " 5月", " 6月", " 7月", " 8月", " 9月", "10月", "11月", "12月"},
+		AM:                "午前",
+		PM:                "午後",
+		DateTime:          "%Y年%m月%d日 %H時%M分%S秒",
+		Date:              "%Y年%m月%d日",
+		Time:              "%H時%M分%S秒",
+		TimeAMPM:          "%p%I時%M分%S秒",
+		Alternatives: Alternatives{
+			Eras: []Era{
+				{Start: time.Date(1, time.January, 1, 0, 0, 0, 0, time.UTC), Offset: 1, Name: "西暦", Format: "%EC%Ey年"},
+				{Start: time.Date(1873, time.January, 1, 0, 0, 0, 0, time.UTC), Offset: 6, Name: "明治", Format: "%EC%Ey年"},
+				{Start: time.Date(1912, time.July, 30, 0, 0, 0, 0, time.UTC), Offset: 1, Name: "大正", Format: "%EC元年"},
+				{Start: time.Date(1913, time.January, 1, 0, 0, 0, 0, time.UTC), Offset: 2, Name: "大正", Format: "%EC%Ey年"},
+				{Start: time.Date(1926, time.December, 25, 0, 0, 0, 0, time.UTC), Offset: 1, Name: "昭和", Format: "%EC元年"},
+				{Start: time.Date(1927, time.January, 1, 0, 0, 0, 0, time.UTC), Offset: 2, Name: "昭和", Format: "%EC%Ey年"},
+				{Start: time.Date(1989, time.January, 8, 0, 0, 0, 0, time.UTC), Offset: 1, Name: "平成", Format: "%EC元年"},
+				{Start: time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC), Offset: 2, Name: "平成", Format: "%EC%Ey年"},
+				{Start: time.Date(2019, time.May, 1, 0, 0, 0, 0, time.UTC), Offset: 1, Name: "令和", Format: "%EC元年"},
+				{Start: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC), Offset: 2, Name: "令和", Format: "%EC%Ey年"},
+			},
+			Digits:      kanjiNumbers(),
+			EraDateTime: "%EY%m月%d日 %H時%M分%S秒",
+			EraDate:     "%EY%m月%d日",
+		},
+		Relative: RelativeNames{
+			Now:    "今",
+			Past:   "%s前",
+			Future: "%s後",
+			Long: [numUnits][2]string{
+				{"%d 秒", "%d 秒"},
+				{"%d 分", "%d 分"},
+				{"%d 時間", "%d 時間"},
+				{"%d 日", "%d 日"},
+				{"%d 週間", "%d 週間"},
+				{"%d か月", "%d か月"},
+				{"%d 年", "%d 年"},
+			},
+			Short: [numUnits][2]string{
+				{"%d 秒", "%d 秒"},
+				{"%d 分", "%d 分"},
+				{"%d 時間", "%d 時間"},
+				{"%d 日", "%d 日"},
+				{"%d 週間", "%d 週間"},
+				{"%d か月", "%d か月"},
+				{"%d 年", "%d 年"},
+			},
+			Narrow: [numUnits][2]string{
+				{"%d秒", "%d秒"},
+				{"%d分", "%d分"},
+				{"%d時間", "%d時間"},
+				{"%d日", "%d日"},
+				{"%d週間", "%d週間"},
+				{"%dか月", "%dか月"},
+				{"%d年", "%d年"},
+			},
+		},
+	},
+}
+
+// kanjiNumbers returns the Japanese representations of the numbers 0
+// through 99.
+func kanjiNumbers() []string {
+	const units = "〇一二三四五六七八九"
+	digits := strings.Split(units, "")
+	numbers := make([]string, 100)
+	for i := range numbers {
+		tens, ones := i/10, i%10
+		var number string
+		switch {
+		case tens == 0:
+			numbers[i] = digits[ones]
+			continue
+		case tens == 1:
+			number = "十"
+		default:
+			number = digits[tens] + "十"
+		}
+		if ones > 0 {
+			number += digits[ones]
+		}
+		numbers[i] = number
+	}
+	return numbers
+}
@@ -0,0 +1,75 @@
+package gosft
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLocales(t *testing.T) {
+	when := time.Date(2021, time.September, 30, 15, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		locale, format, want string
+	}{
+		{"POSIX", "%a %A %b %B %p %P", "Thu Thursday Sep September PM pm"},
+		{"POSIX", "%c|%x|%X|%r", "Thu Sep 30 15:04:05 2021|09/30/21|15:04:05|03:04:05 PM"},
+		{"en_US", "%c|%x|%X", "Thu 30 Sep 2021 03:04:05 PM UTC|09/30/2021|03:04:05 PM"},
+		{"en_GB", "%c|%x|%X|%r", "Thu 30 Sep 2021 15:04:05 UTC|30/09/21|15:04:05| 3:04:05 pm UTC"},
+		{"de_DE", "%a %A %b %B", "Do Donnerstag Sep September"},
+		{"de_DE", "%c|%x|%X", "Do 30 Sep 2021 15:04:05 UTC|30.09.2021|15:04:05"},
+		{"de_DE", "[%p]", "[]"},
+		{"de_DE", "[%r]|%+", "[15:04:05]|Do Sep 30 15:04:05 UTC 2021"},
+		{"fr_FR", "[%r]", "[15:04:05]"},
+		{"es_ES", "[%r]", "[15:04:05]"},
+		{"fr_FR", "%a %A %b %B", "jeu. jeudi sept. septembre"},
+		{"fr_FR", "%c|%x|%X", "jeu. 30 sept. 2021 15:04:05 UTC|30/09/2021|15:04:05"},
+		{"es_ES", "%a %A %b %B", "jue jueves sep septiembre"},
+		{"es_ES", "%c|%x|%X", "jue 30 sep 2021 15:04:05 UTC|30/09/21|15:04:05"},
+		{"ja_JP", "%a %A %b %B", "木 木曜日  9月 9月"},
+		{"ja_JP", "%c|%x|%X|%r", "2021年09月30日 15時04分05秒|2021年09月30日|15時04分05秒|午後03時04分05秒"},
+		{"ja_JP", "%Ec|%Ex|%EX", "令和3年09月30日 15時04分05秒|令和3年09月30日|15時04分05秒"},
+		{"ja_JP", "%EC %Ey %EY", "令和 3 令和3年"},
+		{"ja_JP", "%Om月%Od日 %OH時", "九月三十日 十五時"},
+	}
+
+	for _, c := range tests {
+		t.Run(c.locale+"/"+c.format, func(t *testing.T) {
+			locale, ok := LookupLocale(c.locale)
+			if !ok {
+				t.Fatalf("GOT: %v; WANT: %v", ok, true)
+			}
+
+			tf, err := NewWithLocale(c.format, locale)
+			ensureError(t, err, nil)
+
+			if got, want := tf.Format(when), c.want; got != want {
+				t.Errorf("GOT: %q; WANT: %q", got, want)
+			}
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		if _, ok := LookupLocale("xx_XX"); ok {
+			t.Errorf("GOT: %v; WANT: %v", ok, false)
+		}
+	})
+
+	t.Run("custom", func(t *testing.T) {
+		locale, _ := LookupLocale("POSIX")
+		locale.AbbreviatedDays[4] = "Thr"
+		locale.Date = "%d %b"
+
+		tf, err := NewWithLocale("%x %a", locale)
+		ensureError(t, err, nil)
+
+		if got, want := tf.Format(when), "30 Sep Thr"; got != want {
+			t.Errorf("GOT: %q; WANT: %q", got, want)
+		}
+
+		// Modifying the copy must not change the built-in locale.
+		builtin, _ := LookupLocale("POSIX")
+		if got, want := builtin.AbbreviatedDays[4], "Thu"; got != want {
+			t.Errorf("GOT: %q; WANT: %q", got, want)
+		}
+	})
+}
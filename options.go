@@ -0,0 +1,174 @@
+package gosft
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Option configures a formatter created by NewWithOptions.
+type Option func(*options)
+
+// options holds the configuration of a formatter.
+type options struct {
+	loc      *time.Location
+	locale   *Locale
+	lenient  bool
+	extended bool
+	verbs    map[rune]func([]byte, time.Time) []byte
+	fiscal   time.Month // first month of the fiscal year, or zero for January
+	err      error      // first error of an option, reported by NewWithOptions
+}
+
+// NewWithOptions returns a formatter that formats times according to
+// the provided format string and options. Without options, it is
+// equivalent to New.
+func NewWithOptions(format string, opts ...Option) (*Formatter, error) {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.err != nil {
+		return nil, o.err
+	}
+
+	tf, err := create(format, &o)
+	if err != nil {
+		return nil, err
+	}
+	tf.loc = o.loc
+	return tf, nil
+}
+
+// WithLocation converts each time to loc before formatting it, in the
+// same manner as Formatter.In.
+func WithLocation(loc *time.Location) Option {
+	return func(o *options) {
+		if loc == nil && o.err == nil {
+			o.err = fmt.Errorf("cannot use nil location")
+		}
+		o.loc = loc
+	}
+}
+
+// WithLocale uses locale for the names and layouts of the locale
+// dependent format verbs, in the same manner as NewWithLocale.
+func WithLocale(locale Locale) Option {
+	return func(o *options) {
+		o.locale = &locale
+	}
+}
+
+// WithLenientVerbs causes a format verb that is not recognized, and a
+// '%' character at the end of the format string, to be emitted
+// verbatim, as GNU date(1) does, rather than causing an error. By
+// default, formatters are strict.
+func WithLenientVerbs() Option {
+	return func(o *options) {
+		o.lenient = true
+	}
+}
+
+// WithExtendedVerbs enables the extended format verbs, which are not
+// defined by strftime(3) or date(1), and are named between braces, such
+// as %{fiscal_year}. They are disabled by default so that format strings
+// remain portable to other implementations.
+func WithExtendedVerbs() Option {
+	return func(o *options) {
+		o.extended = true
+	}
+}
+
+// WithFiscalYearStart sets the first month of the fiscal year used by
+// the %{fiscal_year} and %{fiscal_quarter} extended verbs. By default,
+// the fiscal year is the calendar year.
+func WithFiscalYearStart(month time.Month) Option {
+	return func(o *options) {
+		if (month < time.January || month > time.December) && o.err == nil {
+			o.err = fmt.Errorf("cannot use fiscal year start month %d", month)
+		}
+		o.fiscal = month
+	}
+}
+
+// WithVerb registers a custom format verb, formatted by appending the
+// result for t to dst. Custom verbs accept flags and a field width, but
+// may not replace the built-in verbs.
+func WithVerb(verb rune, f func(dst []byte, t time.Time) []byte) Option {
+	return func(o *options) {
+		if err := checkCustomVerb(verb, f); err != nil {
+			if o.err == nil {
+				o.err = err
+			}
+			return
+		}
+		if o.verbs == nil {
+			o.verbs = make(map[rune]func([]byte, time.Time) []byte)
+		}
+		o.verbs[verb] = f
+	}
+}
+
+// checkCustomVerb returns an error when verb cannot be registered as a
+// custom format verb.
+func checkCustomVerb(verb rune, f func([]byte, time.Time) []byte) error {
+	if f == nil {
+		return fmt.Errorf("cannot register format verb %q without a function", verb)
+	}
+	if strings.ContainsRune(formatFlags+"0123456789EO:", verb) {
+		return fmt.Errorf("cannot register format verb %q: it would be read as a flag, field width, or modifier", verb)
+	}
+	if verb == '{' {
+		return fmt.Errorf("cannot register format verb %q: it begins an extended verb", verb)
+	}
+	if verbFormatter(verb) != nil {
+		return fmt.Errorf("cannot register format verb %q: it is a built-in verb", verb)
+	}
+	return nil
+}
+
+// customVerb returns the formatter of a custom format verb registered by
+// the WithVerb option, or else by RegisterVerb, or nil when verb is not
+// a custom verb.
+func (o *options) customVerb(verb rune) func([]byte, time.Time) []byte {
+	if f, ok := o.verbs[verb]; ok {
+		return f
+	}
+	registry.RLock()
+	f := registry.verbs[verb]
+	registry.RUnlock()
+	return f
+}
+
+// registry holds the custom format verbs registered by RegisterVerb.
+var registry struct {
+	sync.RWMutex
+	verbs map[rune]func([]byte, time.Time) []byte
+}
+
+// RegisterVerb registers a custom format verb for all formatters created
+// after it returns, formatted by appending the result for t to dst. It
+// is typically called from an init function. Custom verbs accept flags
+// and a field width, but may not replace the built-in verbs, nor a verb
+// that is already registered. A custom verb registered by the WithVerb
+// option takes precedence over one registered by RegisterVerb. The
+// result of f is included when computing the size of the buffer
+// allocated by Formatter.Format.
+func RegisterVerb(verb rune, f func(dst []byte, t time.Time) []byte) error {
+	if err := checkCustomVerb(verb, f); err != nil {
+		return err
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	if _, ok := registry.verbs[verb]; ok {
+		return fmt.Errorf("cannot register format verb %q: it is already registered", verb)
+	}
+	if registry.verbs == nil {
+		registry.verbs = make(map[rune]func([]byte, time.Time) []byte)
+	}
+	registry.verbs[verb] = f
+	return nil
+}
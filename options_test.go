@@ -0,0 +1,131 @@
+package gosft
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestNewWithOptions(t *testing.T) {
+	when := time.Date(2009, time.February, 5, 5, 0, 57, 12345600, time.UTC)
+
+	quarter := func(dst []byte, t time.Time) []byte {
+		return strconv.AppendInt(dst, int64(t.Month()+2)/3, 10)
+	}
+	milli := func(dst []byte, t time.Time) []byte {
+		return strconv.AppendInt(dst, int64(t.Nanosecond()/1e6), 10)
+	}
+
+	tests := []struct {
+		name, format string
+		opts         []Option
+		want         string
+	}{
+		{"default", "%F %T", nil, "2009-02-05 05:00:57"},
+		{"location", "%F %T %Z", []Option{WithLocation(time.FixedZone("EST", -5*60*60))}, "2009-02-05 00:00:57 EST"},
+		{"locale", "%A %B", []Option{WithLocale(locales["de_DE"])}, "Donnerstag Februar"},
+		{"lenient", "%F %Q %5Q %-&", []Option{WithLenientVerbs()}, "2009-02-05 %Q %5Q %-&"},
+		{"lenient trailing percent", "100%", []Option{WithLenientVerbs()}, "100%"},
+		{"custom", "Q%Q %Y", []Option{WithVerb('Q', quarter)}, "Q1 2009"},
+		{"custom flags", "[%3Q] [%-3Q]", []Option{WithVerb('Q', quarter)}, "[  1] [1]"},
+		{"custom fraction", "%T.%L", []Option{WithVerb('L', milli)}, "05:00:57.12"},
+		{"custom and lenient", "%Q %J", []Option{WithVerb('Q', quarter), WithLenientVerbs()}, "1 %J"},
+	}
+
+	for _, c := range tests {
+		t.Run(c.name, func(t *testing.T) {
+			tf, err := NewWithOptions(c.format, c.opts...)
+			ensureError(t, err, nil)
+			if got, want := tf.Format(when), c.want; got != want {
+				t.Errorf("GOT: %q; WANT: %q", got, want)
+			}
+		})
+	}
+
+	t.Run("extended", func(t *testing.T) {
+		_, err := NewWithOptions("%{half}")
+		ensureError(t, err, errors.New(`cannot recognize extended format verb "half" at index 1`))
+
+		tf, err := NewWithOptions("%Y-H%{half}", WithExtendedVerbs())
+		ensureError(t, err, nil)
+		if got, want := tf.Format(when), "2009-H1"; got != want {
+			t.Errorf("GOT: %q; WANT: %q", got, want)
+		}
+	})
+
+	t.Run("caching", func(t *testing.T) {
+		// The result of a custom verb may change within a second.
+		tf, err := NewWithOptions("%T.%L", WithVerb('L', milli))
+		ensureError(t, err, nil)
+		cf := NewCachingFormatter(tf)
+
+		for _, nsec := range []int{0, 12345600, 999999999} {
+			when := when.Add(time.Duration(nsec - when.Nanosecond()))
+			if got, want := cf.Format(when), tf.Format(when); got != want {
+				t.Errorf("GOT: %q; WANT: %q", got, want)
+			}
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			format string
+			opts   []Option
+			want   string
+		}{
+			{"%Q", nil, "cannot recognize format verb 'Q' at index 1"},
+			{"%F %", nil, "cannot find closing format verb at index 3"},
+			{"%EQ", []Option{WithLenientVerbs()}, "cannot recognize format verb 'Q' with modifier 'E' at index 2"},
+			{"%F", []Option{WithVerb('Y', quarter)}, "cannot register format verb 'Y': it is a built-in verb"},
+			{"%F", []Option{WithVerb('_', quarter)}, "cannot register format verb '_': it would be read as a flag, field width, or modifier"},
+			{"%F", []Option{WithVerb('E', quarter)}, "cannot register format verb 'E': it would be read as a flag, field width, or modifier"},
+			{"%F", []Option{WithVerb('Q', nil)}, "cannot register format verb 'Q' without a function"},
+			{"%F", []Option{WithLocation(nil)}, "cannot use nil location"},
+			{"%F", []Option{WithVerb('{', quarter)}, "cannot register format verb '{': it begins an extended verb"},
+			{"%F", []Option{WithFiscalYearStart(13)}, "cannot use fiscal year start month 13"},
+		}
+
+		for _, c := range tests {
+			_, err := NewWithOptions(c.format, c.opts...)
+			ensureError(t, err, errors.New(c.want))
+		}
+	})
+}
+
+func TestRegisterVerb(t *testing.T) {
+	// Use a verb that no other test, nor a future built-in or extended
+	// verb, is likely to use, because registrations cannot be removed.
+	const verb = '¤'
+
+	name := func(dst []byte, t time.Time) []byte {
+		return append(dst, "Fiscal year ending September"...)
+	}
+	ensureError(t, RegisterVerb(verb, name), nil)
+	ensureError(t, RegisterVerb(verb, name), errors.New("cannot register format verb '¤': it is already registered"))
+	ensureError(t, RegisterVerb('Y', name), errors.New("cannot register format verb 'Y': it is a built-in verb"))
+
+	when := time.Date(2009, time.February, 5, 5, 0, 57, 0, time.UTC)
+
+	tf, err := New("%F %¤ %^¤")
+	ensureError(t, err, nil)
+	want := "2009-02-05 Fiscal year ending September FISCAL YEAR ENDING SEPTEMBER"
+	if got := tf.Format(when); got != want {
+		t.Errorf("GOT: %q; WANT: %q", got, want)
+	}
+
+	// The result of the verb is included in the size of the buffer
+	// allocated by Format.
+	if tf.size < len(want) {
+		t.Errorf("GOT: %d; WANT: >= %d", tf.size, len(want))
+	}
+
+	// A verb registered by the WithVerb option takes precedence.
+	tf, err = NewWithOptions("%¤", WithVerb(verb, func(dst []byte, t time.Time) []byte {
+		return append(dst, "FY"...)
+	}))
+	ensureError(t, err, nil)
+	if got, want := tf.Format(when), "FY"; got != want {
+		t.Errorf("GOT: %q; WANT: %q", got, want)
+	}
+}
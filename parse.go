@@ -0,0 +1,1053 @@
+package gosft
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Parser will parse strings into time.Time values in accordance with
+// their configured format specification. A single Parser may safely be
+// used by multiple Go routines simultaneously.
+type Parser struct {
+	parsers []func(*parseState) error
+}
+
+// NewParser returns a parser that parses times according to the
+// provided format string, which uses the same format verbs as New.
+func NewParser(format string) (*Parser, error) {
+	parsers, err := compileParsers(nil, format)
+	if err != nil {
+		return nil, err
+	}
+	return &Parser{parsers: parsers}, nil
+}
+
+// compositeExpansions maps each composite format verb to the equivalent
+// format string in the POSIX locale, allowing parsers and Go reference
+// layouts to be built from the simpler verbs. It also provides the
+// layouts of the locale dependent verbs when a locale has none.
+var compositeExpansions = map[rune]string{
+	'c': "%a %b %e %H:%M:%S %Y",
+	'D': "%m/%d/%y",
+	'F': "%Y-%m-%d",
+	'r': "%I:%M:%S %p",
+	'R': "%H:%M",
+	'T': "%H:%M:%S",
+	'x': "%m/%d/%y",
+	'X': "%H:%M:%S",
+	'+': "%a %b %e %H:%M:%S %p %Z %Y",
+}
+
+func compileParsers(parsers []func(*parseState) error, format string) ([]func(*parseState) error, error) {
+	var buf []byte
+	var foundPercent bool
+	var percent int // index of the '%' beginning the current verb
+	var modifier rune
+	var flags []byte
+	var width int
+	var colons int
+	var skip int // index following an extended verb
+
+	for ri, rune := range format {
+		if ri < skip {
+			continue
+		}
+		if !foundPercent {
+			if rune == '%' {
+				foundPercent = true
+				percent = ri
+				if len(buf) > 0 {
+					parsers = append(parsers, makeLiteralParser(string(buf)))
+					buf = nil
+				}
+			} else {
+				buf = appendRune(buf, rune)
+			}
+			continue
+		}
+		if modifier == 0 && colons == 0 {
+			if width == 0 && strings.ContainsRune(formatFlags, rune) {
+				flags = append(flags, byte(rune))
+				continue
+			}
+			if rune >= '0' && rune <= '9' {
+				if width = width*10 + int(rune-'0'); width > maxWidth {
+					return nil, fmt.Errorf("cannot use field width greater than %d at index %d", maxWidth, ri)
+				}
+				continue
+			}
+		}
+		if modifier == 0 && (rune == 'E' || rune == 'O') {
+			modifier = rune
+			continue
+		}
+		if modifier == 0 && rune == ':' && colons < 3 {
+			colons++
+			continue
+		}
+		if colons > 0 && rune != 'z' {
+			return nil, fmt.Errorf("cannot use colons with format verb %q at index %d", rune, ri)
+		}
+		if modifier != 0 {
+			// Modified verbs are parsed using the POSIX locale, where
+			// they are equivalent to the unmodified verbs.
+			if !strings.ContainsRune(modifiedVerbs[modifier], rune) {
+				return nil, &UnsupportedModifierError{Format: format, Offset: ri, Modifier: modifier, Verb: rune}
+			}
+			modifier = 0
+		}
+		n := len(parsers)
+		switch rune {
+		case 'a', 'A':
+			parsers = append(parsers, parseWeekday)
+		case 'b', 'B', 'h':
+			parsers = append(parsers, parseMonth)
+		case 'c', 'D', 'F', 'r', 'R', 'T', 'x', 'X', '+':
+			var err error
+			parsers, err = compileParsers(parsers, compositeExpansions[rune])
+			if err != nil {
+				return nil, err
+			}
+		case 'C':
+			parsers = append(parsers, makeCenturyParser(reservedDigits(format[ri+1:])))
+		case 'd':
+			parsers = append(parsers, parseD)
+		case 'e':
+			parsers = append(parsers, parseE)
+		case 'g':
+			parsers = append(parsers, parseG)
+		case 'G':
+			parsers = append(parsers, makeISOYearParser(reservedDigits(format[ri+1:])))
+		case 'H':
+			parsers = append(parsers, parseHC)
+		case 'I':
+			parsers = append(parsers, parseIC)
+		case 'j':
+			parsers = append(parsers, parseJ)
+		case 'k':
+			parsers = append(parsers, parseK)
+		case 'l':
+			parsers = append(parsers, parseL)
+		case 'm':
+			parsers = append(parsers, parseM)
+		case 'M':
+			parsers = append(parsers, parseMC)
+		case 'n', 't':
+			parsers = append(parsers, parseWhitespace)
+		case 'N':
+			parsers = append(parsers, parseNC)
+		case 'p', 'P':
+			parsers = append(parsers, parseP)
+		case 'q':
+			parsers = append(parsers, parseQ)
+		case 's':
+			parsers = append(parsers, parseS)
+		case 'S':
+			parsers = append(parsers, parseSC)
+		case 'u':
+			parsers = append(parsers, parseU)
+		case 'U':
+			parsers = append(parsers, parseUC)
+		case 'V':
+			parsers = append(parsers, parseVC)
+		case 'w':
+			parsers = append(parsers, parseW)
+		case 'W':
+			parsers = append(parsers, parseWC)
+		case 'y':
+			parsers = append(parsers, parseY)
+		case 'Y':
+			parsers = append(parsers, makeYearParser(reservedDigits(format[ri+1:])))
+		case 'K':
+			parsers = append(parsers, parseKC)
+		case 'z':
+			if colons > 0 {
+				parsers = append(parsers, makeOffsetParser(colons))
+			} else {
+				parsers = append(parsers, parseZ)
+			}
+		case 'Z':
+			parsers = append(parsers, parseZC)
+		case '%':
+			parsers = append(parsers, makeLiteralParser("%"))
+		case '{':
+			// Only the extended verbs that identify an instant, such as
+			// %{epoch_ms}, are parsed, and they are always enabled.
+			end := strings.IndexByte(format[ri:], '}')
+			if end < 0 {
+				return nil, &TrailingPercentError{Format: format, Offset: percent}
+			}
+			name := format[ri+1 : ri+end]
+			skip = ri + end + 1
+			ev := extendedVerbs[name]
+			if ev.parse == nil {
+				return nil, &UnknownVerbError{Format: format, Offset: ri, Verb: rune, Name: name}
+			}
+			parsers = append(parsers, ev.parse)
+			if ev.precision > 0 {
+				// The field width is the precision of the result,
+				// which is parsed regardless.
+				flags, width = nil, 0
+			}
+		default:
+			return nil, &UnknownVerbError{Format: format, Offset: ri, Verb: rune}
+		}
+		if len(flags) > 0 || width > 0 {
+			if rune == 'N' {
+				parsers[n] = makeFractionParser(flags, width)
+			} else {
+				// Parse the verb leniently, because flags and field
+				// widths change its padding.
+				relaxed := append([]func(*parseState) error(nil), parsers[n:]...)
+				parsers = append(parsers[:n], makeRelaxedParser(relaxed, width))
+			}
+		}
+		foundPercent = false
+		flags = nil
+		width = 0
+		colons = 0
+	}
+
+	if foundPercent {
+		return nil, &TrailingPercentError{Format: format, Offset: percent}
+	}
+
+	if len(buf) > 0 {
+		parsers = append(parsers, makeLiteralParser(string(buf)))
+	}
+
+	return parsers, nil
+}
+
+// Parse parses value in accordance with its preconfigured format
+// specification and returns the time it represents. In the absence of
+// time zone information, Parse interprets a time as UTC. Elements
+// omitted from the format are assumed to be zero or, when zero is
+// impossible, one, in the same way as time.Parse.
+func (p *Parser) Parse(value string) (time.Time, error) {
+	return p.ParseInLocation(value, time.UTC)
+}
+
+// ParseInLocation is like Parse but differs in two important
+// ways. First, in the absence of time zone information, Parse
+// interprets a time as UTC; ParseInLocation interprets the time as in
+// the given location. Second, when given a zone offset or
+// abbreviation, Parse tries to match it against the UTC location;
+// ParseInLocation uses the given location.
+func (p *Parser) ParseInLocation(value string, loc *time.Location) (time.Time, error) {
+	ps := &parseState{value: value, month: 1, day: 1}
+	for _, f := range p.parsers {
+		if err := f(ps); err != nil {
+			return time.Time{}, err
+		}
+	}
+	if ps.i < len(value) {
+		return time.Time{}, fmt.Errorf("cannot parse %q at index %d: extra text", value, ps.i)
+	}
+	return ps.time(loc)
+}
+
+// parseState holds the input being parsed and the broken-down time
+// fields collected while parsing it.
+type parseState struct {
+	value string
+	i     int // index of the next byte of value to parse
+
+	year, month, day, yday                   int
+	hour, minute, second, nanosecond, offset int
+	century, yy, isoYear, isoYY, weekday     int
+	quarter                                  int
+	weekU, weekV, weekW                      int
+	unix                                     int64
+	zone                                     string
+
+	hasYear, hasCentury, hasYY, hasMonth, hasDay, hasYday bool
+	negativeCentury                                       bool
+	hasISOYear, hasISOYY, hasWeekday                      bool
+	hasWeekU, hasWeekV, hasWeekW, hasQuarter              bool
+	has12, pm, hasOffset, hasZone, hasUnix                bool
+
+	// relaxed is true while parsing a verb having flags or a field
+	// width, which accepts numbers with any padding up to width.
+	relaxed bool
+	width   int
+}
+
+// errorf returns an error that describes what was expected at the
+// current parse position.
+func (ps *parseState) errorf(format string, a ...interface{}) error {
+	return fmt.Errorf("cannot parse %q at index %d: %s", ps.value, ps.i, fmt.Sprintf(format, a...))
+}
+
+// number parses an unsigned decimal number having between minDigits
+// and maxDigits digits, optionally preceded by a single space when
+// space is true, and ensures the result is between min and max.
+func (ps *parseState) number(minDigits, maxDigits, min, max int, space bool) (int, error) {
+	start := ps.i
+	if ps.relaxed {
+		for ps.i < len(ps.value) && ps.value[ps.i] == ' ' {
+			ps.i++
+		}
+		minDigits = 1
+		if ps.width > maxDigits {
+			maxDigits = ps.width
+		}
+	} else if space && ps.i < len(ps.value) && ps.value[ps.i] == ' ' {
+		ps.i++
+		maxDigits--
+	}
+	var n, count int
+	for count < maxDigits && ps.i < len(ps.value) {
+		c := ps.value[ps.i]
+		if c < '0' || c > '9' {
+			break
+		}
+		n = n*10 + int(c-'0')
+		count++
+		ps.i++
+	}
+	if count < minDigits || count == 0 {
+		ps.i = start
+		return 0, ps.errorf("expected %d to %d digits", minDigits, maxDigits)
+	}
+	if n < min || n > max {
+		ps.i = start
+		return 0, ps.errorf("value %d out of range %d to %d", n, min, max)
+	}
+	return n, nil
+}
+
+// fraction parses up to maxDigits digits of fractional seconds,
+// returning the value scaled to nanoseconds.
+func (ps *parseState) fraction(minDigits, maxDigits int) (int, error) {
+	start := ps.i
+	n, err := ps.number(minDigits, maxDigits, 0, 999999999, false)
+	if err != nil {
+		return 0, err
+	}
+	for digits := ps.i - start; digits < 9; digits++ {
+		n *= 10
+	}
+	return n, nil
+}
+
+// signedYear parses the magnitude of a year, or of a century, preceded
+// by a '-' when it is negative. It has between minDigits and maxDigits
+// digits or, when the number of digits reserved for the format verbs
+// that follow it is known, each digit preceding those, up to nine.
+func (ps *parseState) signedYear(minDigits, maxDigits, reserved int) (int, bool, error) {
+	start := ps.i
+	negative := ps.i < len(ps.value) && ps.value[ps.i] == '-'
+	if negative {
+		ps.i++
+	}
+	if reserved >= 0 {
+		var run int
+		for ps.i+run < len(ps.value) && ps.value[ps.i+run] >= '0' && ps.value[ps.i+run] <= '9' {
+			run++
+		}
+		if run -= reserved; run > 9 {
+			maxDigits = 9
+		} else if run > maxDigits {
+			maxDigits = run
+		}
+	}
+	year, err := ps.number(minDigits, maxDigits, 0, 999999999, false)
+	if err != nil {
+		ps.i = start
+		return 0, false, err
+	}
+	return year, negative, nil
+}
+
+// fixedDigitVerbs maps each format verb whose result always has the
+// same number of digits to that number.
+var fixedDigitVerbs = map[byte]int{
+	'd': 2, 'g': 2, 'H': 2, 'I': 2, 'j': 3, 'm': 2, 'M': 2, 'q': 1,
+	'S': 2, 'u': 1, 'U': 2, 'V': 2, 'w': 1, 'W': 2, 'y': 2,
+}
+
+// reservedDigits returns the number of digits formatted by the format
+// verbs at the beginning of rest before a character that cannot be a
+// digit, or -1 when that number is unknown, allowing a year to be
+// parsed using more than four digits when its end can be found.
+func reservedDigits(rest string) int {
+	var reserved int
+	for len(rest) > 0 && rest[0] == '%' {
+		if len(rest) < 2 {
+			return -1
+		}
+		if strings.IndexByte("aAbBhKnpPtzZ%", rest[1]) >= 0 {
+			return reserved
+		}
+		digits, ok := fixedDigitVerbs[rest[1]]
+		if !ok {
+			return -1
+		}
+		reserved += digits
+		rest = rest[2:]
+	}
+	if len(rest) > 0 && rest[0] >= '0' && rest[0] <= '9' {
+		return -1
+	}
+	return reserved
+}
+
+// name parses one of the provided names, ignoring case, and returns its
+// index. Longer names are preferred over their abbreviations.
+func (ps *parseState) name(what string, long string, indices []int, abbreviated int) (int, error) {
+	rest := ps.value[ps.i:]
+	for i := 0; i < len(indices)-1; i++ {
+		name := long[indices[i]:indices[i+1]]
+		if len(rest) >= len(name) && strings.EqualFold(rest[:len(name)], name) {
+			ps.i += len(name)
+			return i, nil
+		}
+	}
+	for i := 0; i < len(indices)-1; i++ {
+		name := long[indices[i] : indices[i]+abbreviated]
+		if len(rest) >= len(name) && strings.EqualFold(rest[:len(name)], name) {
+			ps.i += len(name)
+			return i, nil
+		}
+	}
+	return 0, ps.errorf("expected %s name", what)
+}
+
+// zoneOffset parses a numeric time zone offset and returns the offset
+// in seconds east of UTC. The number of colons selects the form of the
+// offset, as with the format verbs: -0700 for none, -07:00 for one,
+// -07:00:00 for two, and any of -07, -07:00 and -07:00:00 for three.
+func (ps *parseState) zoneOffset(colons int) (int, error) {
+	if ps.i >= len(ps.value) || (ps.value[ps.i] != '+' && ps.value[ps.i] != '-') {
+		return 0, ps.errorf("expected time zone offset")
+	}
+	start := ps.i
+	negative := ps.value[ps.i] == '-'
+	ps.i++
+	hour, err := ps.number(2, 2, 0, 99, false)
+	if err != nil {
+		ps.i = start
+		return 0, err
+	}
+	offset := hour * 3600
+	fields := 1 // minutes, and seconds when there are two or more colons
+	if colons >= 2 {
+		fields = 2
+	}
+	for field, scale := 0, 60; field < fields; field, scale = field+1, 1 {
+		if colons > 0 {
+			if ps.i >= len(ps.value) || ps.value[ps.i] != ':' {
+				if colons == 3 {
+					break
+				}
+				ps.i = start
+				return 0, ps.errorf("expected time zone offset")
+			}
+			ps.i++
+		}
+		n, err := ps.number(2, 2, 0, 59, false)
+		if err != nil {
+			ps.i = start
+			return 0, err
+		}
+		offset += n * scale
+	}
+	if negative {
+		offset = -offset
+	}
+	return offset, nil
+}
+
+// time resolves the fields collected while parsing into a time.Time.
+func (ps *parseState) time(loc *time.Location) (time.Time, error) {
+	if ps.hasUnix {
+		t := time.Unix(ps.unix, int64(ps.nanosecond))
+		if ps.hasOffset {
+			return t.In(time.FixedZone(ps.zone, ps.offset)), nil
+		}
+		return t.In(loc), nil
+	}
+
+	year := ps.year
+	if !ps.hasYear {
+		switch {
+		case ps.hasCentury:
+			// The century has the sign of the year, and the
+			// year of the century its last two digits.
+			if year = ps.century*100 + ps.yy; ps.negativeCentury {
+				year = -year
+			}
+		case ps.hasYY:
+			year = pivotYear(ps.yy)
+		}
+	}
+
+	// When the weekday is omitted, a week number refers to the first
+	// day of that week.
+	weekday := ps.weekday
+	if !ps.hasWeekday && !ps.hasWeekU {
+		weekday = int(time.Monday)
+	}
+
+	month, day := ps.month, ps.day
+	if !ps.hasMonth && !ps.hasDay {
+		switch {
+		case ps.hasYday:
+			if ps.yday > daysInYear(year) {
+				return time.Time{}, fmt.Errorf("cannot parse %q: day of year %d out of range", ps.value, ps.yday)
+			}
+			yd := time.Date(year, time.January, ps.yday, 0, 0, 0, 0, time.UTC)
+			month, day = int(yd.Month()), yd.Day()
+		case ps.hasWeekV:
+			// ISO 8601 weeks begin on Monday, and week 01 is the week
+			// containing January 4th.
+			isoYear := year
+			if ps.hasISOYear {
+				isoYear = ps.isoYear
+			} else if ps.hasISOYY {
+				isoYear = pivotYear(ps.isoYY)
+			}
+			jan4 := time.Date(isoYear, time.January, 4, 0, 0, 0, 0, time.UTC)
+			yday := 4 - (int(jan4.Weekday())+6)%7 + (ps.weekV-1)*7 + (weekday+6)%7
+			wd := time.Date(isoYear, time.January, yday, 0, 0, 0, 0, time.UTC)
+			if _, week := wd.ISOWeek(); week != ps.weekV {
+				return time.Time{}, fmt.Errorf("cannot parse %q: week %d out of range", ps.value, ps.weekV)
+			}
+			year, month, day = wd.Year(), int(wd.Month()), wd.Day()
+		case ps.hasWeekU, ps.hasWeekW:
+			// Week 01 begins on the first Sunday (%U) or Monday (%W)
+			// of the year, and days preceding it belong to week 00.
+			jan1 := int(time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC).Weekday())
+			week, weekday, first := ps.weekU, weekday, (7-jan1)%7
+			if ps.hasWeekW {
+				week, weekday, first = ps.weekW, (weekday+6)%7, (8-jan1)%7
+			}
+			yday := first + (week-1)*7 + weekday + 1
+			if yday < 1 || yday > daysInYear(year) {
+				return time.Time{}, fmt.Errorf("cannot parse %q: week %d out of range", ps.value, week)
+			}
+			wd := time.Date(year, time.January, yday, 0, 0, 0, 0, time.UTC)
+			month, day = int(wd.Month()), wd.Day()
+		case ps.hasQuarter:
+			// A quarter refers to its first day.
+			month = 3*(ps.quarter-1) + 1
+		}
+	}
+	if day > daysInMonth(time.Month(month), year) {
+		return time.Time{}, fmt.Errorf("cannot parse %q: day %d out of range", ps.value, day)
+	}
+
+	hour := ps.hour
+	if ps.has12 {
+		hour %= 12
+		if ps.pm {
+			hour += 12
+		}
+	}
+
+	if ps.hasOffset {
+		t := time.Date(year, time.Month(month), day, hour, ps.minute, ps.second, ps.nanosecond, time.UTC)
+		t = t.Add(-time.Duration(ps.offset) * time.Second)
+		if name, offset := t.In(loc).Zone(); offset == ps.offset && (!ps.hasZone || name == ps.zone) {
+			return t.In(loc), nil
+		}
+		return t.In(time.FixedZone(ps.zone, ps.offset)), nil
+	}
+
+	if ps.hasZone {
+		if ps.zone == "UTC" {
+			return time.Date(year, time.Month(month), day, hour, ps.minute, ps.second, ps.nanosecond, time.UTC), nil
+		}
+		t := time.Date(year, time.Month(month), day, hour, ps.minute, ps.second, ps.nanosecond, loc)
+		if name, _ := t.Zone(); name == ps.zone {
+			return t, nil
+		}
+		return time.Date(year, time.Month(month), day, hour, ps.minute, ps.second, ps.nanosecond, time.FixedZone(ps.zone, 0)), nil
+	}
+
+	return time.Date(year, time.Month(month), day, hour, ps.minute, ps.second, ps.nanosecond, loc), nil
+}
+
+// pivotYear returns the year having the last two digits yy. POSIX:
+// values 69–99 refer to years in the twentieth century, and values
+// 00–68 refer to years in the twenty-first century.
+func pivotYear(yy int) int {
+	if yy < 69 {
+		return 2000 + yy
+	}
+	return 1900 + yy
+}
+
+func isLeap(year int) bool {
+	return year%4 == 0 && (year%100 != 0 || year%400 == 0)
+}
+
+func daysInYear(year int) int {
+	if isLeap(year) {
+		return 366
+	}
+	return 365
+}
+
+func daysInMonth(month time.Month, year int) int {
+	switch month {
+	case time.February:
+		if isLeap(year) {
+			return 29
+		}
+		return 28
+	case time.April, time.June, time.September, time.November:
+		return 30
+	default:
+		return 31
+	}
+}
+
+func makeRelaxedParser(parsers []func(*parseState) error, width int) func(*parseState) error {
+	return func(ps *parseState) error {
+		for ps.i < len(ps.value) && ps.value[ps.i] == ' ' {
+			ps.i++
+		}
+		ps.relaxed, ps.width = true, width
+		defer func() { ps.relaxed, ps.width = false, 0 }()
+		for _, f := range parsers {
+			if err := f(ps); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+func makeFractionParser(flags []byte, width int) func(*parseState) error {
+	if width == 0 {
+		width = 9
+	}
+	precision := width
+	if precision > 9 {
+		precision = 9
+	}
+	var pad byte
+	for _, flag := range flags {
+		switch flag {
+		case '-', '_', '0':
+			pad = flag
+		}
+	}
+
+	return func(ps *parseState) error {
+		start := ps.i
+		nanosecond, err := ps.fraction(1, precision)
+		if err != nil {
+			return err
+		}
+		if pad == '-' {
+			// Trimmed fractions have no padding.
+			ps.nanosecond = nanosecond
+			return nil
+		}
+		trimmed := pad == '_'
+		// Consume the padding following the digits.
+		for ps.i-start < width && ps.i < len(ps.value) {
+			if c := ps.value[ps.i]; c != '0' && !(trimmed && c == ' ') {
+				break
+			}
+			ps.i++
+		}
+		ps.nanosecond = nanosecond
+		return nil
+	}
+}
+
+func makeLiteralParser(value string) func(*parseState) error {
+	return func(ps *parseState) error {
+		if !strings.HasPrefix(ps.value[ps.i:], value) {
+			return ps.errorf("expected %q", value)
+		}
+		ps.i += len(value)
+		return nil
+	}
+}
+
+func parseWeekday(ps *parseState) error {
+	// %a and %A both accept either the full or abbreviated weekday
+	// name, ignoring case.
+	weekday, err := ps.name("weekday", weekdaysLong, weekdaysLongIndices, 3)
+	if err != nil {
+		return err
+	}
+	ps.weekday = weekday
+	ps.hasWeekday = true
+	return nil
+}
+
+func parseMonth(ps *parseState) error {
+	// %b, %B and %h all accept either the full or abbreviated month
+	// name, ignoring case.
+	month, err := ps.name("month", monthsLong, monthsLongIndices, 3)
+	if err != nil {
+		return err
+	}
+	ps.month = month + 1
+	ps.hasMonth = true
+	return nil
+}
+
+func makeCenturyParser(reserved int) func(*parseState) error {
+	return func(ps *parseState) error {
+		century, negative, err := ps.signedYear(1, 2, reserved)
+		if err != nil {
+			return err
+		}
+		ps.century = century
+		ps.negativeCentury = negative
+		ps.hasCentury = true
+		return nil
+	}
+}
+
+func parseD(ps *parseState) error {
+	day, err := ps.number(1, 2, 1, 31, false)
+	if err != nil {
+		return err
+	}
+	ps.day = day
+	ps.hasDay = true
+	return nil
+}
+
+func parseE(ps *parseState) error {
+	day, err := ps.number(1, 2, 1, 31, true)
+	if err != nil {
+		return err
+	}
+	ps.day = day
+	ps.hasDay = true
+	return nil
+}
+
+func parseG(ps *parseState) error {
+	year, err := ps.number(2, 2, 0, 99, false)
+	if err != nil {
+		return err
+	}
+	ps.isoYY = year
+	ps.hasISOYY = true
+	return nil
+}
+
+func makeISOYearParser(reserved int) func(*parseState) error {
+	return func(ps *parseState) error {
+		year, negative, err := ps.signedYear(4, 4, reserved)
+		if err != nil {
+			return err
+		}
+		if negative {
+			year = -year
+		}
+		ps.isoYear = year
+		ps.hasISOYear = true
+		return nil
+	}
+}
+
+func parseHC(ps *parseState) error {
+	hour, err := ps.number(1, 2, 0, 23, false)
+	if err != nil {
+		return err
+	}
+	ps.hour = hour
+	return nil
+}
+
+func parseIC(ps *parseState) error {
+	hour, err := ps.number(1, 2, 1, 12, false)
+	if err != nil {
+		return err
+	}
+	ps.hour = hour
+	ps.has12 = true
+	return nil
+}
+
+func parseJ(ps *parseState) error {
+	yday, err := ps.number(1, 3, 1, 366, false)
+	if err != nil {
+		return err
+	}
+	ps.yday = yday
+	ps.hasYday = true
+	return nil
+}
+
+func parseK(ps *parseState) error {
+	hour, err := ps.number(1, 2, 0, 23, true)
+	if err != nil {
+		return err
+	}
+	ps.hour = hour
+	return nil
+}
+
+func parseL(ps *parseState) error {
+	hour, err := ps.number(1, 2, 1, 12, true)
+	if err != nil {
+		return err
+	}
+	ps.hour = hour
+	ps.has12 = true
+	return nil
+}
+
+func parseM(ps *parseState) error {
+	month, err := ps.number(1, 2, 1, 12, false)
+	if err != nil {
+		return err
+	}
+	ps.month = month
+	ps.hasMonth = true
+	return nil
+}
+
+func parseMC(ps *parseState) error {
+	minute, err := ps.number(1, 2, 0, 59, false)
+	if err != nil {
+		return err
+	}
+	ps.minute = minute
+	return nil
+}
+
+func parseWhitespace(ps *parseState) error {
+	// %n and %t match arbitrary white space, as they do for
+	// strptime(3).
+	for ps.i < len(ps.value) {
+		switch ps.value[ps.i] {
+		case ' ', '\t', '\n', '\v', '\f', '\r':
+			ps.i++
+			continue
+		}
+		break
+	}
+	return nil
+}
+
+func parseNC(ps *parseState) error {
+	nanosecond, err := ps.fraction(1, 9)
+	if err != nil {
+		return err
+	}
+	ps.nanosecond = nanosecond
+	return nil
+}
+
+func parseP(ps *parseState) error {
+	rest := ps.value[ps.i:]
+	if len(rest) >= 2 {
+		switch {
+		case strings.EqualFold(rest[:2], "AM"):
+			ps.pm = false
+			ps.i += 2
+			return nil
+		case strings.EqualFold(rest[:2], "PM"):
+			ps.pm = true
+			ps.i += 2
+			return nil
+		}
+	}
+	return ps.errorf("expected AM or PM")
+}
+
+func parseQ(ps *parseState) error {
+	quarter, err := ps.number(1, 1, 1, 4, false)
+	if err != nil {
+		return err
+	}
+	ps.quarter = quarter
+	ps.hasQuarter = true
+	return nil
+}
+
+func parseS(ps *parseState) error {
+	start := ps.i
+	negative := ps.i < len(ps.value) && ps.value[ps.i] == '-'
+	if negative {
+		ps.i++
+	}
+	var n int64
+	var count int
+	for ps.i < len(ps.value) && ps.value[ps.i] >= '0' && ps.value[ps.i] <= '9' {
+		n = n*10 + int64(ps.value[ps.i]-'0')
+		count++
+		ps.i++
+	}
+	if count == 0 || count > 18 {
+		ps.i = start
+		return ps.errorf("expected number of seconds since the Epoch")
+	}
+	if negative {
+		n = -n
+	}
+	ps.unix = n
+	ps.hasUnix = true
+	return nil
+}
+
+func parseSC(ps *parseState) error {
+	second, err := ps.number(1, 2, 0, 60, false)
+	if err != nil {
+		return err
+	}
+	ps.second = second
+	return nil
+}
+
+func parseU(ps *parseState) error {
+	weekday, err := ps.number(1, 1, 1, 7, false)
+	if err != nil {
+		return err
+	}
+	ps.weekday = weekday % 7
+	ps.hasWeekday = true
+	return nil
+}
+
+func parseUC(ps *parseState) error {
+	week, err := ps.number(1, 2, 0, 53, false)
+	if err != nil {
+		return err
+	}
+	ps.weekU = week
+	ps.hasWeekU = true
+	return nil
+}
+
+func parseVC(ps *parseState) error {
+	week, err := ps.number(1, 2, 1, 53, false)
+	if err != nil {
+		return err
+	}
+	ps.weekV = week
+	ps.hasWeekV = true
+	return nil
+}
+
+func parseW(ps *parseState) error {
+	weekday, err := ps.number(1, 1, 0, 6, false)
+	if err != nil {
+		return err
+	}
+	ps.weekday = weekday
+	ps.hasWeekday = true
+	return nil
+}
+
+func parseWC(ps *parseState) error {
+	week, err := ps.number(1, 2, 0, 53, false)
+	if err != nil {
+		return err
+	}
+	ps.weekW = week
+	ps.hasWeekW = true
+	return nil
+}
+
+func parseY(ps *parseState) error {
+	year, err := ps.number(2, 2, 0, 99, false)
+	if err != nil {
+		return err
+	}
+	ps.yy = year
+	ps.hasYY = true
+	return nil
+}
+
+func makeYearParser(reserved int) func(*parseState) error {
+	return func(ps *parseState) error {
+		year, negative, err := ps.signedYear(4, 4, reserved)
+		if err != nil {
+			return err
+		}
+		if negative {
+			year = -year
+		}
+		ps.year = year
+		ps.hasYear = true
+		return nil
+	}
+}
+
+func parseZ(ps *parseState) error {
+	// Accept both -0700 and -07:00 forms, as well as Z for UTC.
+	if ps.i < len(ps.value) && ps.value[ps.i] == 'Z' {
+		ps.i++
+		ps.offset = 0
+		ps.hasOffset = true
+		return nil
+	}
+	var colons int
+	if ps.i+3 < len(ps.value) && ps.value[ps.i+3] == ':' {
+		colons = 1
+	}
+	offset, err := ps.zoneOffset(colons)
+	if err != nil {
+		return err
+	}
+	ps.offset = offset
+	ps.hasOffset = true
+	return nil
+}
+
+func parseZC(ps *parseState) error {
+	// Time zone abbreviations are either alphabetic, such as MST, or
+	// numeric, such as +0530.
+	start := ps.i
+	for ps.i < len(ps.value) {
+		c := ps.value[ps.i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') ||
+			(ps.i > start && c >= '0' && c <= '9') ||
+			(ps.i == start && (c == '+' || c == '-')) {
+			ps.i++
+			continue
+		}
+		break
+	}
+	if ps.i == start {
+		return ps.errorf("expected time zone abbreviation")
+	}
+	ps.zone = ps.value[start:ps.i]
+	ps.hasZone = true
+	return nil
+}
+
+func parseKC(ps *parseState) error {
+	if ps.i < len(ps.value) && ps.value[ps.i] == 'Z' {
+		ps.i++
+		ps.offset = 0
+		ps.hasOffset = true
+		return nil
+	}
+	offset, err := ps.zoneOffset(1)
+	if err != nil {
+		return err
+	}
+	ps.offset = offset
+	ps.hasOffset = true
+	return nil
+}
+
+// makeOffsetParser returns the parser for %:z, %::z or %:::z, having the
+// provided number of colons.
+func makeOffsetParser(colons int) func(*parseState) error {
+	return func(ps *parseState) error {
+		offset, err := ps.zoneOffset(colons)
+		if err != nil {
+			return err
+		}
+		ps.offset = offset
+		ps.hasOffset = true
+		return nil
+	}
+}
@@ -0,0 +1,189 @@
+package gosft
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParser(t *testing.T) {
+	tests := []struct {
+		format, value string
+		want          time.Time
+	}{
+		{"%F %T", "2006-01-02 15:04:05", time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC)},
+		{"%Y%m%d%H%M%S", "20060102150405", time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC)},
+		{"%c", "Mon Jan  2 15:04:05 2006", time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC)},
+		{"%A, %d %B %Y", "monday, 02 JANUARY 2006", time.Date(2006, time.January, 2, 0, 0, 0, 0, time.UTC)},
+		{"%a %b %e", "Monday Sep  2", time.Date(0, time.September, 2, 0, 0, 0, 0, time.UTC)},
+		{"%D", "01/02/06", time.Date(2006, time.January, 2, 0, 0, 0, 0, time.UTC)},
+		{"%D", "01/02/69", time.Date(1969, time.January, 2, 0, 0, 0, 0, time.UTC)},
+		{"%C%y-%m-%d", "1901-02-03", time.Date(1901, time.February, 3, 0, 0, 0, 0, time.UTC)},
+		{"%F", "12345-03-04", time.Date(12345, time.March, 4, 0, 0, 0, 0, time.UTC)},
+		{"%C%y", "-0044", time.Date(-44, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"%Y %j", "2006 032", time.Date(2006, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"%r", "12:04:05 AM", time.Date(0, time.January, 1, 0, 4, 5, 0, time.UTC)},
+		{"%r", "12:04:05 PM", time.Date(0, time.January, 1, 12, 4, 5, 0, time.UTC)},
+		{"%l:%M %P", " 3:04 pm", time.Date(0, time.January, 1, 15, 4, 0, 0, time.UTC)},
+		{"%k:%M", " 3:04", time.Date(0, time.January, 1, 3, 4, 0, 0, time.UTC)},
+		{"%T.%N", "15:04:05.123456789", time.Date(0, time.January, 1, 15, 4, 5, 123456789, time.UTC)},
+		{"%T.%N", "15:04:05.12", time.Date(0, time.January, 1, 15, 4, 5, 120000000, time.UTC)},
+		{"%s", "1136214245", time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC)},
+		{"%s", "-1", time.Date(1969, time.December, 31, 23, 59, 59, 0, time.UTC)},
+		{"%F%n%T", "2006-01-02 \t15:04:05", time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC)},
+		{"%F %T %z", "2006-01-02 15:04:05 -0700", time.Date(2006, time.January, 2, 15, 4, 5, 0, time.FixedZone("", -7*60*60))},
+		{"%F %T %z", "2006-01-02 15:04:05 +05:30", time.Date(2006, time.January, 2, 15, 4, 5, 0, time.FixedZone("", 5*60*60+30*60))},
+		{"%FT%T%K", "2006-01-02T15:04:05Z", time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC)},
+		{"%FT%T%K", "2006-01-02T15:04:05-09:30", time.Date(2006, time.January, 2, 15, 4, 5, 0, time.FixedZone("", -(9*60*60+30*60)))},
+		{"%T %:z", "15:04:05 +05:45", time.Date(0, time.January, 1, 15, 4, 5, 0, time.FixedZone("", 5*60*60+45*60))},
+		{"%T %::z", "15:04:05 -04:56:02", time.Date(0, time.January, 1, 15, 4, 5, 0, time.FixedZone("", -(4*60*60+56*60+2)))},
+		{"%T %:::z", "15:04:05 -04", time.Date(0, time.January, 1, 15, 4, 5, 0, time.FixedZone("", -4*60*60))},
+		{"%T %:::z", "15:04:05 +05:30", time.Date(0, time.January, 1, 15, 4, 5, 0, time.FixedZone("", 5*60*60+30*60))},
+		{"%T %:::z", "15:04:05 -04:56:02", time.Date(0, time.January, 1, 15, 4, 5, 0, time.FixedZone("", -(4*60*60+56*60+2)))},
+		{"%F %T %Z", "2006-01-02 15:04:05 UTC", time.Date(2006, time.January, 2, 15, 4, 5, 0, time.UTC)},
+		{"%Y %U %a", "2005 00 Sat", time.Date(2005, time.January, 1, 0, 0, 0, 0, time.UTC)},
+		{"%Y %U %a", "2007 52 Mon", time.Date(2007, time.December, 31, 0, 0, 0, 0, time.UTC)},
+		{"%Y %W %u", "2007 53 1", time.Date(2007, time.December, 31, 0, 0, 0, 0, time.UTC)},
+		{"%Y %W", "2012 01", time.Date(2012, time.January, 2, 0, 0, 0, 0, time.UTC)},
+		{"%G-W%V-%u", "2009-W53-4", time.Date(2009, time.December, 31, 0, 0, 0, 0, time.UTC)},
+		{"%G-W%V-%u", "2009-W53-7", time.Date(2010, time.January, 3, 0, 0, 0, 0, time.UTC)},
+		{"%G-W%V", "2009-W01", time.Date(2008, time.December, 29, 0, 0, 0, 0, time.UTC)},
+		{"%g-W%V-%u", "99-W01-1", time.Date(1999, time.January, 4, 0, 0, 0, 0, time.UTC)},
+		{"%g-W%V-%u", "09-W53-4", time.Date(2009, time.December, 31, 0, 0, 0, 0, time.UTC)},
+		{"%y %g-W%V", "99 99-W01", time.Date(1999, time.January, 4, 0, 0, 0, 0, time.UTC)},
+		{"%Y-Q%q", "2009-Q3", time.Date(2009, time.July, 1, 0, 0, 0, 0, time.UTC)},
+		{"%F Q%q", "2009-02-05 Q1", time.Date(2009, time.February, 5, 0, 0, 0, 0, time.UTC)},
+		{"%-m/%-d/%Y %-I:%M %p", "1/2/2006 3:04 PM", time.Date(2006, time.January, 2, 15, 4, 0, 0, time.UTC)},
+		{"%_m/%_d/%_10Y", " 1/ 2/      2006", time.Date(2006, time.January, 2, 0, 0, 0, 0, time.UTC)},
+		{"%10a %^b %-d", "    Monday JAN 2", time.Date(0, time.January, 2, 0, 0, 0, 0, time.UTC)},
+		{"%_20F", "          2006-01-02", time.Date(2006, time.January, 2, 0, 0, 0, 0, time.UTC)},
+		{"%T.%3N", "15:04:05.123", time.Date(0, time.January, 1, 15, 4, 5, 123000000, time.UTC)},
+		{"%T.%_6N|", "15:04:05.12    |", time.Date(0, time.January, 1, 15, 4, 5, 120000000, time.UTC)},
+		{"%T.%12N", "15:04:05.123000000000", time.Date(0, time.January, 1, 15, 4, 5, 123000000, time.UTC)},
+		{"%T.%-3N %Y", "15:04:05.12 2006", time.Date(2006, time.January, 1, 15, 4, 5, 120000000, time.UTC)},
+		{"%T.%-N", "15:04:05.0", time.Date(0, time.January, 1, 15, 4, 5, 0, time.UTC)},
+		{"100%% %Y", "100% 2006", time.Date(2006, time.January, 1, 0, 0, 0, 0, time.UTC)},
+	}
+
+	for _, c := range tests {
+		t.Run(c.format+"/"+c.value, func(t *testing.T) {
+			p, err := NewParser(c.format)
+			ensureError(t, err, nil)
+
+			got, err := p.Parse(c.value)
+			ensureError(t, err, nil)
+
+			if !got.Equal(c.want) {
+				t.Errorf("GOT: %v; WANT: %v", got, c.want)
+			}
+			_, gotOffset := got.Zone()
+			_, wantOffset := c.want.Zone()
+			if gotOffset != wantOffset {
+				t.Errorf("GOT: %v; WANT: %v", gotOffset, wantOffset)
+			}
+		})
+	}
+}
+
+func TestParserYears(t *testing.T) {
+	formats := []string{"%Y", "%F", "%c", "%C%y", "%C%y-%m-%d", "%Y%m%d", "%G-W%V-%u", "%_6Y-%m-%d"}
+
+	for _, year := range []int{-44, 0, 2006, 10000, 12345} {
+		want := time.Date(year, time.March, 4, 0, 0, 0, 0, time.UTC)
+		for _, format := range formats {
+			tf, err := New(format)
+			ensureError(t, err, nil)
+			p, err := NewParser(format)
+			ensureError(t, err, nil)
+
+			value := tf.Format(want)
+			got, err := p.Parse(value)
+			ensureError(t, err, nil)
+
+			if format == "%Y" || format == "%C%y" {
+				if got.Year() != year {
+					t.Errorf("%s: %q: GOT: %v; WANT: %v", format, value, got.Year(), year)
+				}
+			} else if !got.Equal(want) {
+				t.Errorf("%s: %q: GOT: %v; WANT: %v", format, value, got, want)
+			}
+		}
+	}
+}
+
+func TestParserErrors(t *testing.T) {
+	tests := []struct {
+		format, value, want string
+	}{
+		{"%F", "2006-1x-02", "at index 6"},
+		{"%F", "2006-13-02", "value 13 out of range"},
+		{"%F", "2006-02-30", "day 30 out of range"},
+		{"%Y %j", "2006 366", "day of year 366 out of range"},
+		{"%H:%M", "15:04 extra", "at index 5: extra text"},
+		{"%a", "Xyz", "expected weekday name"},
+		{"%p", "XM", "expected AM or PM"},
+		{"%z", "0700", "expected time zone offset"},
+		{"%:z", "+0700", "expected time zone offset"},
+		{"%::z", "+07:00", "expected time zone offset"},
+		{"%K", "z", "expected time zone offset"},
+		{"%G-W%V", "2010-W53", "week 53 out of range"},
+		{"%Y %U %a", "2005 00 Sun", "week 0 out of range"},
+		{"abc", "abd", "expected \"abc\""},
+	}
+
+	for _, c := range tests {
+		t.Run(c.format+"/"+c.value, func(t *testing.T) {
+			p, err := NewParser(c.format)
+			ensureError(t, err, nil)
+
+			_, err = p.Parse(c.value)
+			ensureError(t, err, errors.New(c.want))
+		})
+	}
+
+	t.Run("format", func(t *testing.T) {
+		_, err := NewParser("%Q")
+		ensureError(t, err, errors.New("cannot recognize format verb 'Q' at index 1"))
+
+		_, err = NewParser("%:d")
+		ensureError(t, err, errors.New("cannot use colons with format verb 'd' at index 2"))
+
+		_, err = NewParser("%F %")
+		ensureError(t, err, errors.New("cannot find closing format verb"))
+	})
+}
+
+func TestParserRoundTrip(t *testing.T) {
+	times := []time.Time{
+		time.Date(2006, time.January, 2, 3, 4, 5, 12345678, time.UTC),
+		time.Date(2021, time.September, 30, 23, 59, 59, 123456789, time.UTC),
+		time.Date(1999, time.December, 31, 0, 0, 0, 0, time.UTC),
+	}
+
+	for layout, format := range formatMap {
+		tf, err := New(format)
+		ensureError(t, err, nil)
+		p, err := NewParser(format)
+		ensureError(t, err, nil)
+
+		for _, when := range times {
+			t.Run(layout+"/"+when.String(), func(t *testing.T) {
+				formatted := tf.Format(when)
+
+				got, err := p.Parse(formatted)
+				ensureError(t, err, nil)
+
+				if g, w := tf.Format(got), formatted; g != w {
+					t.Errorf("GOT: %q; WANT: %q", g, w)
+				}
+
+				// The standard library must agree with the parsed value.
+				want, err := time.Parse(layout, formatted)
+				ensureError(t, err, nil)
+				if !got.Equal(want) {
+					t.Errorf("GOT: %v; WANT: %v", got, want)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,456 @@
+package gosft
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// opcode identifies the operation performed by an instruction of a
+// compiled program.
+type opcode uint8
+
+const (
+	opText      opcode = iota // append the text of the instruction
+	opCall                    // append the result of the function of the instruction
+	opSubsecond               // like opCall, for a function whose result may change within a second
+
+	// The remaining opcodes format a field of the broken-down time.
+	opWeekdayShort // %a
+	opWeekdayLong  // %A
+	opMonthShort   // %b, %h
+	opMonthLong    // %B
+	opCentury      // %C
+	opDay          // %d
+	opDaySpace     // %e
+	opDayMin       // layout element 2
+	opHour         // %H
+	opHourSpace    // %k
+	opHour12       // %I
+	opHour12Space  // %l
+	opHour12Min    // layout element 3
+	opYearDay      // %j
+	opYearDaySpace // layout element __2
+	opMonth        // %m
+	opMonthMin     // layout element 1
+	opMinute       // %M
+	opMinuteMin    // layout element 4
+	opNanosecond   // %N
+	opAMPM         // %p
+	opAMPMLower    // %P
+	opQuarter      // %q
+	opUnix         // %s
+	opSecond       // %S
+	opSecondMin    // layout element 5
+	opWeekdayISO   // %u
+	opWeekSunday   // %U
+	opWeekday      // %w
+	opWeekMonday   // %W
+	opYear2        // %y
+	opYear         // %Y
+	opOffset       // %z
+	opZoneName     // %Z
+)
+
+// instruction is a single step of a compiled program.
+type instruction struct {
+	op   opcode
+	text string                         // text for opText
+	call func([]byte, time.Time) []byte // function for opCall and opSubsecond
+}
+
+// verbOpcodes maps each format verb that has a dedicated opcode to that
+// opcode. Other verbs, and verbs having flags, a field width, a
+// modifier, or a locale, are formatted by calling a function.
+var verbOpcodes = map[rune]opcode{
+	'a': opWeekdayShort,
+	'A': opWeekdayLong,
+	'b': opMonthShort,
+	'B': opMonthLong,
+	'C': opCentury,
+	'd': opDay,
+	'e': opDaySpace,
+	'h': opMonthShort,
+	'H': opHour,
+	'I': opHour12,
+	'j': opYearDay,
+	'k': opHourSpace,
+	'l': opHour12Space,
+	'm': opMonth,
+	'M': opMinute,
+	'N': opNanosecond,
+	'p': opAMPM,
+	'P': opAMPMLower,
+	'q': opQuarter,
+	's': opUnix,
+	'S': opSecond,
+	'u': opWeekdayISO,
+	'U': opWeekSunday,
+	'w': opWeekday,
+	'W': opWeekMonday,
+	'y': opYear2,
+	'Y': opYear,
+	'z': opOffset,
+	'Z': opZoneName,
+}
+
+// verbTexts maps each format verb that always emits the same text to
+// that text.
+var verbTexts = map[rune]string{
+	'n': "\n",
+	't': "\t",
+	'%': "%",
+}
+
+// appendText appends an instruction emitting text to program, combining
+// it with a preceding instruction that also emits text.
+func appendText(program []instruction, text string) []instruction {
+	if n := len(program); n > 0 && program[n-1].op == opText {
+		program[n-1].text += text
+		return program
+	}
+	return append(program, instruction{op: opText, text: text})
+}
+
+// needs records which parts of the broken-down time are used by a
+// program, so that only those parts are computed.
+type needs uint8
+
+const (
+	needDate needs = 1 << iota
+	needClock
+	needWeekday
+	needZone
+	needUnix
+	needTime // the time.Time itself, for functions called by opCall
+)
+
+// opcodeNeeds maps each opcode to the parts of the broken-down time it
+// uses.
+var opcodeNeeds = [...]needs{
+	opCall:         needTime,
+	opSubsecond:    needTime,
+	opWeekdayShort: needWeekday,
+	opWeekdayLong:  needWeekday,
+	opMonthShort:   needDate,
+	opMonthLong:    needDate,
+	opCentury:      needDate,
+	opDay:          needDate,
+	opDaySpace:     needDate,
+	opDayMin:       needDate,
+	opHour:         needClock,
+	opHourSpace:    needClock,
+	opHour12:       needClock,
+	opHour12Space:  needClock,
+	opHour12Min:    needClock,
+	opYearDay:      needDate,
+	opYearDaySpace: needDate,
+	opMonth:        needDate,
+	opMonthMin:     needDate,
+	opMinute:       needClock,
+	opMinuteMin:    needClock,
+	opAMPM:         needClock,
+	opAMPMLower:    needClock,
+	opQuarter:      needDate,
+	opSecond:       needClock,
+	opSecondMin:    needClock,
+	opWeekdayISO:   needWeekday,
+	opWeekSunday:   needDate | needWeekday,
+	opWeekday:      needWeekday,
+	opWeekMonday:   needDate | needWeekday,
+	opYear2:        needDate,
+	opYear:         needDate,
+	opUnix:         needUnix,
+	opOffset:       needZone,
+	opZoneName:     needZone,
+}
+
+// programNeeds returns the parts of the broken-down time used by
+// program.
+func programNeeds(program []instruction) needs {
+	var n needs
+	for _, in := range program {
+		n |= opcodeNeeds[in.op]
+	}
+	return n
+}
+
+// daysBefore holds the number of days in a common year preceding the
+// first day of each month.
+var daysBefore = [...]int{0, 31, 59, 90, 120, 151, 181, 212, 243, 273, 304, 334}
+
+// fields holds the broken-down time used by the opcodes of a program.
+type fields struct {
+	year, yday, day      int
+	month                time.Month
+	weekday              time.Weekday
+	hour, minute, second int
+	nanosecond           int
+	unix                 int64
+	name                 string
+	offset               int
+}
+
+// Append will format t in accordance with its preconfigured format
+// specification and append the formatted bytes to buf.
+func (tf *Formatter) Append(buf []byte, t time.Time) []byte {
+	if tf.loc != nil {
+		t = t.In(tf.loc)
+	}
+	f := breakDown(t, tf.needs)
+	return run(buf, tf.program, t, &f)
+}
+
+// breakDown returns the parts of the broken-down time of t specified by
+// n, computing each of them once rather than once per verb.
+func breakDown(t time.Time, n needs) fields {
+	f := fields{nanosecond: t.Nanosecond()}
+
+	if n&needDate != 0 {
+		f.year, f.month, f.day = t.Date()
+		f.yday = yearDay(f.year, f.month, f.day)
+	}
+	if n&needClock != 0 {
+		f.hour, f.minute, f.second = t.Clock()
+	}
+	if n&needWeekday != 0 {
+		f.weekday = t.Weekday()
+	}
+	if n&needZone != 0 {
+		f.name, f.offset = t.Zone()
+	}
+	if n&needUnix != 0 {
+		f.unix = t.Unix()
+	}
+
+	return f
+}
+
+// AppendUnix will format the time sec seconds after the Unix epoch, as
+// observed in a fixed time zone offset seconds east of UTC, and append
+// the formatted bytes to buf. It computes the calendar fields directly,
+// without constructing a time.Time or looking up a time.Location, and
+// produces the same result as Append for time.Unix(sec, 0).In(loc),
+// where loc is time.UTC when offset is zero, and otherwise a fixed zone
+// without a name. Verbs formatted with flags, a field width, a locale,
+// or a modifier still construct a time.Time. When the formatter has a
+// location set by In, the time is instead converted to that location,
+// and offset is ignored.
+func (tf *Formatter) AppendUnix(buf []byte, sec int64, offset int) []byte {
+	return tf.appendUnix(buf, sec, 0, offset)
+}
+
+// AppendNanos will format the time nsec nanoseconds after the Unix
+// epoch, as observed in a fixed time zone offset seconds east of UTC,
+// and append the formatted bytes to buf. See AppendUnix.
+func (tf *Formatter) AppendNanos(buf []byte, nsec int64, offset int) []byte {
+	sec := nsec / 1e9
+	if nsec %= 1e9; nsec < 0 {
+		nsec += 1e9
+		sec--
+	}
+	return tf.appendUnix(buf, sec, nsec, offset)
+}
+
+func (tf *Formatter) appendUnix(buf []byte, sec, nsec int64, offset int) []byte {
+	if tf.loc != nil {
+		return tf.Append(buf, time.Unix(sec, nsec))
+	}
+
+	f := fields{nanosecond: int(nsec), unix: sec, offset: offset}
+	if offset == 0 {
+		f.name = "UTC"
+	}
+
+	// Split the local time into days since the epoch and seconds since
+	// midnight, rounding toward negative infinity.
+	local := sec + int64(offset)
+	days := local / secondsPerDay
+	if local%secondsPerDay < 0 {
+		days--
+	}
+	clock := int(local - days*secondsPerDay)
+
+	if tf.needs&needDate != 0 {
+		f.year, f.month, f.day = civilDate(days)
+		f.yday = yearDay(f.year, f.month, f.day)
+	}
+	if tf.needs&needClock != 0 {
+		f.hour, f.minute, f.second = clock/3600, clock/60%60, clock%60
+	}
+	if tf.needs&needWeekday != 0 {
+		// The Unix epoch was a Thursday.
+		weekday := (days + int64(time.Thursday)) % 7
+		if weekday < 0 {
+			weekday += 7
+		}
+		f.weekday = time.Weekday(weekday)
+	}
+
+	var t time.Time
+	if tf.needs&needTime != 0 {
+		t = time.Unix(sec, nsec).In(fixedZone(offset))
+	}
+
+	return run(buf, tf.program, t, &f)
+}
+
+const secondsPerDay = 24 * 60 * 60
+
+// civilDate returns the proleptic Gregorian calendar date of the day
+// days after 1970-01-01, using the algorithm described by Howard Hinnant
+// in "chrono-Compatible Low-Level Date Algorithms".
+func civilDate(days int64) (int, time.Month, int) {
+	days += 719468 // shift the epoch to 0000-03-01
+	era := days / 146097
+	if days%146097 < 0 {
+		era--
+	}
+	doe := days - era*146097                               // day of era, [0, 146096]
+	yoe := (doe - doe/1460 + doe/36524 - doe/146096) / 365 // year of era, [0, 399]
+	doy := doe - (365*yoe + yoe/4 - yoe/100)               // day of year starting March 1, [0, 365]
+	mp := (5*doy + 2) / 153                                // month starting March, [0, 11]
+	day := int(doy - (153*mp+2)/5 + 1)
+	month := int(mp + 3)
+	if month > 12 {
+		month -= 12
+	}
+	year := int(yoe + era*400)
+	if month <= 2 {
+		year++
+	}
+	return year, time.Month(month), day
+}
+
+// yearDay returns the day of the year of the provided date, from 1 to
+// 366.
+func yearDay(year int, month time.Month, day int) int {
+	yday := daysBefore[month-1] + day
+	if month > time.February && isLeap(year) {
+		yday++
+	}
+	return yday
+}
+
+// fixedZones caches the locations returned by fixedZone.
+var fixedZones sync.Map // map[int]*time.Location
+
+// fixedZone returns time.UTC when offset is zero, and otherwise a fixed
+// zone without a name that is offset seconds east of UTC.
+func fixedZone(offset int) *time.Location {
+	if offset == 0 {
+		return time.UTC
+	}
+	if loc, ok := fixedZones.Load(offset); ok {
+		return loc.(*time.Location)
+	}
+	loc, _ := fixedZones.LoadOrStore(offset, time.FixedZone("", offset))
+	return loc.(*time.Location)
+}
+
+// run executes program, appending the result to buf. Opcodes format the
+// broken-down time in f, while functions called by opCall and
+// opSubsecond format t.
+func run(buf []byte, program []instruction, t time.Time, f *fields) []byte {
+	for i := range program {
+		in := &program[i]
+
+		switch in.op {
+		case opText:
+			buf = append(buf, in.text...)
+		case opCall, opSubsecond:
+			buf = in.call(buf, t)
+		case opWeekdayShort:
+			index := weekdaysLongIndices[f.weekday]
+			buf = append(buf, weekdaysLong[index:index+3]...)
+		case opWeekdayLong:
+			buf = append(buf, weekdaysLong[weekdaysLongIndices[f.weekday]:weekdaysLongIndices[f.weekday+1]]...)
+		case opMonthShort:
+			index := monthsLongIndices[f.month-1]
+			buf = append(buf, monthsLong[index:index+3]...)
+		case opMonthLong:
+			buf = append(buf, monthsLong[monthsLongIndices[f.month-1]:monthsLongIndices[f.month]]...)
+		case opCentury:
+			buf = appendCentury(buf, f.year)
+		case opDay:
+			buf = append2DigitsZero(buf, f.day)
+		case opDaySpace:
+			buf = append2DigitsSpace(buf, f.day)
+		case opDayMin:
+			buf = append2DigitsMin(buf, f.day)
+		case opHour:
+			buf = append2DigitsZero(buf, f.hour)
+		case opHourSpace:
+			buf = append2DigitsSpace(buf, f.hour)
+		case opHour12, opHour12Space, opHour12Min:
+			hour12 := f.hour % 12
+			if hour12 == 0 {
+				hour12 = 12
+			}
+			switch in.op {
+			case opHour12:
+				buf = append2DigitsZero(buf, hour12)
+			case opHour12Space:
+				buf = append2DigitsSpace(buf, hour12)
+			default:
+				buf = append2DigitsMin(buf, hour12)
+			}
+		case opYearDay:
+			buf = append3DigitsZero(buf, f.yday)
+		case opYearDaySpace:
+			buf = appendYearDaySpace(buf, f.yday)
+		case opMonth:
+			buf = append2DigitsZero(buf, int(f.month))
+		case opMonthMin:
+			buf = append2DigitsMin(buf, int(f.month))
+		case opMinute:
+			buf = append2DigitsZero(buf, f.minute)
+		case opMinuteMin:
+			buf = append2DigitsMin(buf, f.minute)
+		case opNanosecond:
+			buf = append9DigitsZero(buf, f.nanosecond)
+		case opAMPM:
+			if f.hour < 12 {
+				buf = append(buf, "AM"...)
+			} else {
+				buf = append(buf, "PM"...)
+			}
+		case opAMPMLower:
+			if f.hour < 12 {
+				buf = append(buf, "am"...)
+			} else {
+				buf = append(buf, "pm"...)
+			}
+		case opQuarter:
+			buf = append(buf, byte((f.month+2)/3+'0'))
+		case opUnix:
+			buf = strconv.AppendInt(buf, f.unix, 10)
+		case opSecond:
+			buf = append2DigitsZero(buf, f.second)
+		case opSecondMin:
+			buf = append2DigitsMin(buf, f.second)
+		case opWeekdayISO:
+			if f.weekday > 0 {
+				buf = append(buf, byte(f.weekday+'0'))
+			} else {
+				buf = append(buf, '7')
+			}
+		case opWeekSunday:
+			buf = append2DigitsZero(buf, (f.yday+6-int(f.weekday))/7)
+		case opWeekday:
+			buf = append(buf, byte(f.weekday+'0'))
+		case opWeekMonday:
+			buf = append2DigitsZero(buf, (f.yday+6-(int(f.weekday)+6)%7)/7)
+		case opYear2:
+			buf = append2DigitsZero(buf, yearOfCentury(f.year))
+		case opYear:
+			buf = appendYear(buf, f.year)
+		case opOffset:
+			buf = appendOffset(buf, f.offset)
+		case opZoneName:
+			buf = append(buf, f.name...)
+		}
+	}
+
+	return buf
+}
@@ -0,0 +1,251 @@
+package gosft
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Unit identifies a unit of time used by a RelativeFormatter.
+type Unit int
+
+// The units of time used by a RelativeFormatter, from the smallest to
+// the largest.
+const (
+	Seconds Unit = iota
+	Minutes
+	Hours
+	Days
+	Weeks
+	Months
+	Years
+
+	numUnits = iota
+)
+
+// unitSeconds holds the length of each unit in seconds, using the
+// average lengths of the months and years of the Gregorian calendar.
+var unitSeconds = [numUnits]float64{1, 60, 60 * 60, secondsPerDay, 7 * secondsPerDay, 365.2425 * secondsPerDay / 12, 365.2425 * secondsPerDay}
+
+// RelativeStyle selects the length of the unit names used by a
+// RelativeFormatter.
+type RelativeStyle int
+
+// The styles of a RelativeFormatter, which format three minutes as "3
+// minutes", "3 min" and "3m" respectively in the POSIX locale.
+const (
+	LongStyle RelativeStyle = iota
+	ShortStyle
+	NarrowStyle
+)
+
+// RelativeNames provides the words used by a RelativeFormatter.
+type RelativeNames struct {
+	// Now is used when the time is less than one of the smallest
+	// enabled unit from now, after rounding, such as "now".
+	Now string
+
+	// Past and Future are the patterns used for times before and
+	// after now, in which %s is replaced by the amount of time, such
+	// as "%s ago" and "in %s".
+	Past, Future string
+
+	// Long, Short and Narrow hold the singular and plural form of each
+	// unit, starting with Seconds, in which %d is replaced by the
+	// number of units, such as "%d minute" and "%d minutes".
+	Long, Short, Narrow [numUnits][2]string
+}
+
+// RelativeFormatter will format times relative to another time, such as
+// "3 minutes ago" or "in 2 days", in accordance with its configured
+// options. A single RelativeFormatter may safely be used by multiple Go
+// routines simultaneously.
+type RelativeFormatter struct {
+	now          string
+	past, future pattern
+	units        [numUnits][2]pattern
+	thresholds   [numUnits]int
+	round        func(float64) float64
+}
+
+// pattern is a string split around its placeholder.
+type pattern struct {
+	prefix, suffix string
+}
+
+// RelativeOption configures a formatter created by
+// NewRelativeFormatter.
+type RelativeOption func(*relativeOptions)
+
+// relativeOptions holds the configuration of a relative formatter.
+type relativeOptions struct {
+	names      *RelativeNames
+	style      RelativeStyle
+	thresholds [numUnits]int
+	round      func(float64) float64
+	err        error // first error of an option, reported by NewRelativeFormatter
+}
+
+// defaultThresholds holds the number of each unit at which the next
+// larger unit is used instead, where zero disables the unit.
+var defaultThresholds = [numUnits]int{
+	Seconds: 45,
+	Minutes: 45,
+	Hours:   22,
+	Days:    26,
+	Months:  11,
+}
+
+// NewRelativeFormatter returns a formatter that formats times relative
+// to another time using the provided options. By default, it uses the
+// long style and the names of the POSIX locale, rounds to the nearest
+// whole unit, and uses the smallest unit of which there are fewer than
+// 45 seconds, 45 minutes, 22 hours, 26 days or 11 months, or else years,
+// so that 50 minutes is "1 hour ago".
+func NewRelativeFormatter(opts ...RelativeOption) (*RelativeFormatter, error) {
+	o := relativeOptions{thresholds: defaultThresholds, round: math.Round}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.err != nil {
+		return nil, o.err
+	}
+
+	names := o.names
+	if names == nil || names.Past == "" {
+		names = &posixLocale.Relative
+	}
+	units := [...]*[numUnits][2]string{LongStyle: &names.Long, ShortStyle: &names.Short, NarrowStyle: &names.Narrow}[o.style]
+
+	rf := &RelativeFormatter{now: names.Now, thresholds: o.thresholds, round: o.round}
+	var err error
+	if rf.past, err = splitPattern(names.Past, "%s"); err != nil {
+		return nil, err
+	}
+	if rf.future, err = splitPattern(names.Future, "%s"); err != nil {
+		return nil, err
+	}
+	for unit := range units {
+		for i, form := range units[unit] {
+			if rf.units[unit][i], err = splitPattern(form, "%d"); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return rf, nil
+}
+
+// splitPattern splits s around its placeholder.
+func splitPattern(s, placeholder string) (pattern, error) {
+	i := strings.Index(s, placeholder)
+	if i < 0 {
+		return pattern{}, fmt.Errorf("cannot find %s in relative time pattern %q", placeholder, s)
+	}
+	return pattern{prefix: s[:i], suffix: s[i+len(placeholder):]}, nil
+}
+
+// WithRelativeStyle selects the length of the unit names. By default,
+// the long style is used.
+func WithRelativeStyle(style RelativeStyle) RelativeOption {
+	return func(o *relativeOptions) {
+		if (style < LongStyle || style > NarrowStyle) && o.err == nil {
+			o.err = fmt.Errorf("cannot use relative style %d", style)
+		}
+		o.style = style
+	}
+}
+
+// WithRelativeLocale uses the relative time names of locale. When
+// locale has no relative time names, those of the POSIX locale are used.
+func WithRelativeLocale(locale Locale) RelativeOption {
+	return func(o *relativeOptions) {
+		o.names = &locale.Relative
+	}
+}
+
+// WithThreshold sets the number of the provided unit at which the next
+// larger enabled unit is used instead, unless there would be less than
+// one of that unit. A threshold of zero disables unit, and the threshold
+// of Years is ignored, because there is no larger unit. Weeks are
+// disabled by default, so WithThreshold(Days, 7) together with
+// WithThreshold(Weeks, 4) causes 8 days to be formatted as "1 week ago"
+// rather than "8 days ago".
+func WithThreshold(unit Unit, threshold int) RelativeOption {
+	return func(o *relativeOptions) {
+		if unit < Seconds || unit > Years {
+			if o.err == nil {
+				o.err = fmt.Errorf("cannot use unit %d", unit)
+			}
+			return
+		}
+		if threshold < 0 && o.err == nil {
+			o.err = fmt.Errorf("cannot use negative threshold %d", threshold)
+		}
+		o.thresholds[unit] = threshold
+	}
+}
+
+// WithRounding uses round to convert the amount of time to a whole
+// number of units, such as math.Floor, which formats 90 seconds as "1
+// minute ago" rather than "2 minutes ago". By default, math.Round is
+// used.
+func WithRounding(round func(float64) float64) RelativeOption {
+	return func(o *relativeOptions) {
+		if round == nil && o.err == nil {
+			o.err = fmt.Errorf("cannot use nil rounding function")
+		}
+		o.round = round
+	}
+}
+
+// Append will format t relative to now in accordance with the options
+// of the RelativeFormatter and append the formatted bytes to buf.
+func (rf *RelativeFormatter) Append(buf []byte, t, now time.Time) []byte {
+	d := t.Sub(now)
+	seconds := math.Abs(d.Seconds())
+
+	unit, count := Years, rf.round(seconds/unitSeconds[Years])
+	previous, previousCount := Unit(-1), 0.0 // largest smaller enabled unit
+	for u := Seconds; u < Years; u++ {
+		if rf.thresholds[u] == 0 {
+			continue
+		}
+		c := rf.round(seconds / unitSeconds[u])
+		if c < float64(rf.thresholds[u]) {
+			unit, count = u, c
+			break
+		}
+		previous, previousCount = u, c
+	}
+	if count < 1 {
+		if previous < 0 {
+			return append(buf, rf.now...)
+		}
+		// The amount of time exceeds the threshold of the previous
+		// unit, but is less than one of this unit.
+		unit, count = previous, previousCount
+	}
+
+	direction := rf.past
+	if d > 0 {
+		direction = rf.future
+	}
+	form := rf.units[unit][1]
+	if count == 1 {
+		form = rf.units[unit][0]
+	}
+
+	buf = append(buf, direction.prefix...)
+	buf = append(buf, form.prefix...)
+	buf = strconv.AppendInt(buf, int64(count), 10)
+	buf = append(buf, form.suffix...)
+	return append(buf, direction.suffix...)
+}
+
+// Format will format t relative to now and return a string in
+// accordance with the options of the RelativeFormatter.
+func (rf *RelativeFormatter) Format(t, now time.Time) string {
+	return string(rf.Append(nil, t, now))
+}
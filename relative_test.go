@@ -0,0 +1,158 @@
+package gosft
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestRelativeFormatter(t *testing.T) {
+	now := time.Date(2026, time.March, 5, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "now"},
+		{-400 * time.Millisecond, "now"},
+		{-time.Second, "1 second ago"},
+		{44 * time.Second, "in 44 seconds"},
+		{-45 * time.Second, "1 minute ago"},
+		{-90 * time.Second, "2 minutes ago"},
+		{-44 * time.Minute, "44 minutes ago"},
+		{-50 * time.Minute, "1 hour ago"},
+		{3 * time.Hour, "in 3 hours"},
+		{-22 * time.Hour, "1 day ago"},
+		{-8 * 24 * time.Hour, "8 days ago"},
+		{2 * 24 * time.Hour, "in 2 days"},
+		{-26 * 24 * time.Hour, "1 month ago"},
+		{-300 * 24 * time.Hour, "10 months ago"},
+		{-330 * 24 * time.Hour, "1 year ago"},
+		{5 * 365 * 24 * time.Hour, "in 5 years"},
+	}
+
+	rf, err := NewRelativeFormatter()
+	ensureError(t, err, nil)
+
+	for _, c := range tests {
+		if got, want := rf.Format(now.Add(c.d), now), c.want; got != want {
+			t.Errorf("%v: GOT: %q; WANT: %q", c.d, got, want)
+		}
+	}
+
+	t.Run("styles", func(t *testing.T) {
+		tests := []struct {
+			style RelativeStyle
+			want  string
+		}{
+			{LongStyle, "3 minutes ago"},
+			{ShortStyle, "3 min ago"},
+			{NarrowStyle, "3m ago"},
+		}
+
+		for _, c := range tests {
+			rf, err := NewRelativeFormatter(WithRelativeStyle(c.style))
+			ensureError(t, err, nil)
+			if got, want := rf.Format(now.Add(-3*time.Minute), now), c.want; got != want {
+				t.Errorf("GOT: %q; WANT: %q", got, want)
+			}
+		}
+	})
+
+	t.Run("locales", func(t *testing.T) {
+		tests := []struct {
+			locale string
+			style  RelativeStyle
+			d      time.Duration
+			want   string
+		}{
+			{"en_GB", LongStyle, -time.Minute, "1 minute ago"},
+			{"de_DE", LongStyle, -3 * 24 * time.Hour, "vor 3 Tagen"},
+			{"de_DE", ShortStyle, 2 * time.Hour, "in 2 Std."},
+			{"fr_FR", LongStyle, -3 * time.Minute, "il y a 3 minutes"},
+			{"fr_FR", LongStyle, 0, "maintenant"},
+			{"es_ES", LongStyle, 2 * 24 * time.Hour, "dentro de 2 días"},
+			{"ja_JP", LongStyle, -3 * time.Minute, "3 分前"},
+			{"ja_JP", NarrowStyle, 2 * time.Hour, "2時間後"},
+		}
+
+		for _, c := range tests {
+			locale, _ := LookupLocale(c.locale)
+			rf, err := NewRelativeFormatter(WithRelativeLocale(locale), WithRelativeStyle(c.style))
+			ensureError(t, err, nil)
+			if got, want := rf.Format(now.Add(c.d), now), c.want; got != want {
+				t.Errorf("%s: GOT: %q; WANT: %q", c.locale, got, want)
+			}
+		}
+
+		// A locale without relative names uses those of the POSIX
+		// locale.
+		rf, err := NewRelativeFormatter(WithRelativeLocale(Locale{}))
+		ensureError(t, err, nil)
+		if got, want := rf.Format(now.Add(time.Minute), now), "in 1 minute"; got != want {
+			t.Errorf("GOT: %q; WANT: %q", got, want)
+		}
+	})
+
+	t.Run("options", func(t *testing.T) {
+		tests := []struct {
+			name string
+			opts []RelativeOption
+			d    time.Duration
+			want string
+		}{
+			{"floor", []RelativeOption{WithRounding(math.Floor)}, -90 * time.Second, "1 minute ago"},
+			{"ceil", []RelativeOption{WithRounding(math.Ceil)}, -61 * time.Second, "2 minutes ago"},
+			{"weeks", []RelativeOption{WithThreshold(Days, 7), WithThreshold(Weeks, 4)}, -8 * 24 * time.Hour, "1 week ago"},
+			{"no seconds", []RelativeOption{WithThreshold(Seconds, 0)}, -10 * time.Second, "now"},
+			{"no weeks", []RelativeOption{WithThreshold(Days, 7)}, -8 * 24 * time.Hour, "8 days ago"},
+			{"no months", []RelativeOption{WithThreshold(Months, 0)}, -60 * 24 * time.Hour, "60 days ago"},
+			{"no months or days", []RelativeOption{WithThreshold(Months, 0), WithThreshold(Days, 0)}, -60 * 24 * time.Hour, "1440 hours ago"},
+			{"no months, a year", []RelativeOption{WithThreshold(Months, 0)}, -300 * 24 * time.Hour, "1 year ago"},
+			{"minutes", []RelativeOption{WithThreshold(Minutes, 120)}, -90 * time.Minute, "90 minutes ago"},
+		}
+
+		for _, c := range tests {
+			t.Run(c.name, func(t *testing.T) {
+				rf, err := NewRelativeFormatter(c.opts...)
+				ensureError(t, err, nil)
+				if got, want := rf.Format(now.Add(c.d), now), c.want; got != want {
+					t.Errorf("GOT: %q; WANT: %q", got, want)
+				}
+			})
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		tests := []struct {
+			opts []RelativeOption
+			want string
+		}{
+			{[]RelativeOption{WithRelativeStyle(3)}, "cannot use relative style 3"},
+			{[]RelativeOption{WithThreshold(7, 1)}, "cannot use unit 7"},
+			{[]RelativeOption{WithThreshold(Days, -1)}, "cannot use negative threshold -1"},
+			{[]RelativeOption{WithRounding(nil)}, "cannot use nil rounding function"},
+			{[]RelativeOption{WithRelativeLocale(Locale{Relative: RelativeNames{Past: "ago"}})}, `cannot find %s in relative time pattern "ago"`},
+		}
+
+		for _, c := range tests {
+			_, err := NewRelativeFormatter(c.opts...)
+			ensureError(t, err, errors.New(c.want))
+		}
+	})
+}
+
+func BenchmarkRelativeFormatter(b *testing.B) {
+	rf, err := NewRelativeFormatter()
+	ensureError(b, err, nil)
+	now := time.Now()
+	then := now.Add(-3 * time.Minute)
+
+	b.ReportAllocs()
+	var buf []byte
+	for i := 0; i < b.N; i++ {
+		buf = rf.Append(buf[:0], then, now)
+	}
+	_ = buf
+}